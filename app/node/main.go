@@ -1,12 +1,14 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -18,6 +20,10 @@ import (
 	"github.com/indexus/go-indexus-core/http/monitoring"
 	"github.com/indexus/go-indexus-core/http/p2p"
 	"github.com/indexus/go-indexus-core/peer"
+	"github.com/indexus/go-indexus-core/security"
+	"github.com/indexus/go-indexus-core/storage"
+	"github.com/indexus/go-indexus-core/webhook"
+	"github.com/indexus/go-indexus-core/wire"
 	"github.com/indexus/go-indexus-core/worker"
 )
 
@@ -45,9 +51,49 @@ type Config struct {
 	BootstrapFlag      string
 	NameFlag           string
 	MonitoringPortFlag int
+	MonitoringHostFlag string
 	P2pPortFlag        int
 	ClientPortFlag     int
 	StorageFlag        string
+	IdentityFlag       string
+	DifficultyFlag     int
+	MineFlag           bool
+	TokensFlag         string
+	TLSFlag            bool
+	TLSCertFlag        string
+	TLSKeyFlag         string
+	TLSCAFlag          string
+	MTLSFlag           bool
+	RebalanceFlag      float64
+	TimeoutFlag        time.Duration
+	WorkersFlag        int
+	RetriesFlag        int
+	BackoffFlag        time.Duration
+	SuspicionFlag      int
+	QueueFlag          int
+	FeedersFlag        int
+	HopsFlag           int
+	AckWaitFlag        time.Duration
+	DedupKeysFlag      int
+	DedupWindowFlag    time.Duration
+	QueueWaitFlag      time.Duration
+	JournalFlag        string
+	CacheEntriesFlag   int
+	CacheBytesFlag     int
+	CacheNegativeFlag  time.Duration
+	PushDelayFlag      time.Duration
+	PollIntervalFlag   time.Duration
+	WebhooksFlag       string
+	WebhookRetriesFlag int
+	WebhookBackoffFlag time.Duration
+	WebhookPrivateFlag bool
+	ReadLimitFlag      float64
+	WriteLimitFlag     float64
+	ProxiesFlag        string
+	PeerLimitFlag      float64
+	Identity           *domain.Identity
+	Tokens             *domain.Tokens
+	Proxies            []*net.IPNet
 	Bootstraps         []domain.Contact
 }
 
@@ -78,10 +124,47 @@ func main() {
 // parseFlags handles command-line flag parsing and returns a Config struct
 func parseFlags() Config {
 	bootstrapFlagPtr := flag.String("bootstrap", "", "Host of the bootstrap peers")
-	nameFlagPtr := flag.String("name", domain.EncodeId(domain.RandomId()), "Name of the node")
+	nameFlagPtr := flag.String("name", "", "Name of the node, derived from the identity when empty")
 	monitoringPortFlagPtr := flag.Int("monitoringPort", 19000, "Port number of the node for the monitoring service")
+	monitoringHostFlagPtr := flag.String("monitoringHost", "127.0.0.1", "Address the monitoring service listens on, empty for every interface")
 	p2pPortFlagPtr := flag.Int("p2pPort", 21000, "Port number of the node for the peer to peer network")
 	storageFlagPtr := flag.String("storage", ".data/backup", "Path to the backup file")
+	identityFlagPtr := flag.String("identity", "", "Path to the identity file, in the storage directory when empty")
+	difficultyFlagPtr := flag.Int("difficulty", 0, "Proof of work required from the node ids, as leading zero bits, 0 to disable")
+	mineFlagPtr := flag.Bool("mine", false, "Mine a new nonce, which changes the name of the node, when the identity lacks the work of -difficulty")
+	tokensFlagPtr := flag.String("tokens", "", "Path to the bearer tokens of the client endpoints, open to anyone when empty")
+	tlsFlagPtr := flag.Bool("tls", false, "Serve the p2p and monitoring ports over TLS")
+	tlsCertFlagPtr := flag.String("tlsCert", "", "Path to the TLS certificate, derived from the identity when empty")
+	tlsKeyFlagPtr := flag.String("tlsKey", "", "Path to the key of the TLS certificate")
+	tlsCAFlagPtr := flag.String("tlsCA", "", "Path to the certificate authorities trusted for peer certificates")
+	mtlsFlagPtr := flag.Bool("mtls", false, "Require the identity certificate of the peers on the peer endpoints")
+	timeoutFlagPtr := flag.Duration("timeout", 200*time.Millisecond, "Deadline of every call to a peer")
+	workersFlagPtr := flag.Int("workers", 16, "Number of peers called concurrently by the recurring jobs")
+	retriesFlagPtr := flag.Int("retries", 3, "Number of attempts of a call to a peer or of the insertion of an item")
+	backoffFlagPtr := flag.Duration("backoff", 50*time.Millisecond, "Backoff before retrying a call to a peer or an insertion, doubled on every retry")
+	suspicionFlagPtr := flag.Int("suspicion", 3, "Number of failed calls after which a suspected peer is rejected")
+	queueFlagPtr := flag.Int("queue", 100_000, "Capacity of the ingestion queue, 0 for no bound")
+	feedersFlagPtr := flag.Int("feeders", 4, "Number of collections whose items are inserted concurrently")
+	hopsFlagPtr := flag.Int("maxHops", 32, "Number of nodes an item may go through before being given up")
+	ackWaitFlagPtr := flag.Duration("ackWait", 30*time.Second, "Longest wait of a client for the insertion of an item")
+	dedupKeysFlagPtr := flag.Int("dedupKeys", 100_000, "Number of idempotency keys remembered by the node, 0 disables the deduplication")
+	dedupWindowFlagPtr := flag.Duration("dedupWindow", time.Hour, "Time an idempotency key is remembered by the node")
+	queueWaitFlagPtr := flag.Duration("queueWait", 0, "Time an item waits for room in the full ingestion queue before being refused")
+	journalFlagPtr := flag.String("journal", "", "Path to the journal of the ingestion queue, replayed after a crash, kept in memory only when empty")
+	cacheEntriesFlagPtr := flag.Int("cacheEntries", 10_000, "Number of sets of other nodes cached, 0 for no bound")
+	cacheBytesFlagPtr := flag.Int("cacheBytes", 64<<20, "Approximate bytes of the sets of other nodes cached, 0 for no bound")
+	cacheNegativeFlagPtr := flag.Duration("cacheNegative", 30*time.Second, "Time an area found empty is cached")
+	pushDelayFlagPtr := flag.Duration("pushDelay", 100*time.Millisecond, "Delay gathering the changes of the sets of the node before they are pushed to the peers caching them")
+	pollIntervalFlagPtr := flag.Duration("pollInterval", time.Minute, "Interval of the polling of the cached sets pushed by their owners")
+	webhooksFlagPtr := flag.String("webhooks", "", "Path to the webhooks registered on the node, kept in memory only when empty")
+	webhookRetriesFlagPtr := flag.Int("webhookRetries", 5, "Number of attempts of the delivery of an event to a webhook")
+	webhookBackoffFlagPtr := flag.Duration("webhookBackoff", time.Second, "Backoff before retrying the delivery of an event to a webhook, doubled on every retry")
+	webhookPrivateFlagPtr := flag.Bool("webhookPrivate", false, "Allow webhooks to loopback, link-local and private addresses")
+	readLimitFlagPtr := flag.Float64("readLimit", 100, "Reads per second allowed to every client, token or peer, 0 to disable")
+	writeLimitFlagPtr := flag.Float64("writeLimit", 100, "Writes per second allowed to every client, token or peer, 0 to disable")
+	peerLimitFlagPtr := flag.Float64("peerLimit", 20, "Peer maintenance calls per second allowed to every peer, 0 to disable")
+	proxiesFlagPtr := flag.String("proxies", "", "Comma-separated addresses or CIDR ranges of the reverse proxies whose forwarding headers give the client IP")
+	rebalanceFlagPtr := flag.Float64("rebalance", 2, "Load factor above which hot areas are handed to less loaded peers, 0 to disable")
 
 	flag.Parse()
 
@@ -96,16 +179,102 @@ func parseFlags() Config {
 			if err != nil {
 				log.Fatal(err)
 			}
-			bootstraps = append(bootstraps, peer.NewContact(domain.EncodeId(make([]byte, domain.IdLength())), map[string]any{ip: nil}, port))
+			bootstraps = append(bootstraps, peer.NewBinaryContact(domain.EncodeId(make([]byte, domain.IdLength())), map[string]any{ip: nil}, port))
+		}
+	}
+
+	proxies := make([]*net.IPNet, 0)
+	for _, value := range strings.Split(*proxiesFlagPtr, ",") {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			log.Fatal(err)
+		}
+		proxies = append(proxies, network)
+	}
+
+	if len(*identityFlagPtr) == 0 {
+		*identityFlagPtr = filepath.Join(filepath.Dir(*storageFlagPtr), "identity.pem")
+	}
+
+	identity, err := storage.LoadIdentity(*identityFlagPtr, *difficultyFlagPtr, *mineFlagPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(*nameFlagPtr) == 0 {
+		*nameFlagPtr = identity.Name()
+	}
+
+	var tokens *domain.Tokens
+	if len(*tokensFlagPtr) > 0 {
+		// Peers are told apart from the clients by their work only, they have
+		// no token to forward items and fetch sets
+		if *difficultyFlagPtr <= 0 {
+			log.Fatal("-tokens requires a positive -difficulty")
+		}
+		tokens, err = storage.LoadTokens(*tokensFlagPtr)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
 
 	return Config{
 		BootstrapFlag:      *bootstrapFlagPtr,
 		NameFlag:           *nameFlagPtr,
+		IdentityFlag:       *identityFlagPtr,
+		DifficultyFlag:     *difficultyFlagPtr,
+		MineFlag:           *mineFlagPtr,
+		TokensFlag:         *tokensFlagPtr,
+		TLSFlag:            *tlsFlagPtr || *mtlsFlagPtr,
+		TLSCertFlag:        *tlsCertFlagPtr,
+		TLSKeyFlag:         *tlsKeyFlagPtr,
+		TLSCAFlag:          *tlsCAFlagPtr,
+		MTLSFlag:           *mtlsFlagPtr,
 		MonitoringPortFlag: *monitoringPortFlagPtr,
+		MonitoringHostFlag: *monitoringHostFlagPtr,
 		P2pPortFlag:        *p2pPortFlagPtr,
 		StorageFlag:        *storageFlagPtr,
+		RebalanceFlag:      *rebalanceFlagPtr,
+		TimeoutFlag:        *timeoutFlagPtr,
+		WorkersFlag:        *workersFlagPtr,
+		RetriesFlag:        *retriesFlagPtr,
+		BackoffFlag:        *backoffFlagPtr,
+		SuspicionFlag:      *suspicionFlagPtr,
+		QueueFlag:          *queueFlagPtr,
+		FeedersFlag:        *feedersFlagPtr,
+		HopsFlag:           *hopsFlagPtr,
+		AckWaitFlag:        *ackWaitFlagPtr,
+		DedupKeysFlag:      *dedupKeysFlagPtr,
+		DedupWindowFlag:    *dedupWindowFlagPtr,
+		QueueWaitFlag:      *queueWaitFlagPtr,
+		JournalFlag:        *journalFlagPtr,
+		CacheEntriesFlag:   *cacheEntriesFlagPtr,
+		CacheBytesFlag:     *cacheBytesFlagPtr,
+		CacheNegativeFlag:  *cacheNegativeFlagPtr,
+		PushDelayFlag:      *pushDelayFlagPtr,
+		PollIntervalFlag:   *pollIntervalFlagPtr,
+		WebhooksFlag:       *webhooksFlagPtr,
+		WebhookRetriesFlag: *webhookRetriesFlagPtr,
+		WebhookBackoffFlag: *webhookBackoffFlagPtr,
+		WebhookPrivateFlag: *webhookPrivateFlagPtr,
+		ReadLimitFlag:      *readLimitFlagPtr,
+		WriteLimitFlag:     *writeLimitFlagPtr,
+		PeerLimitFlag:      *peerLimitFlagPtr,
+		ProxiesFlag:        *proxiesFlagPtr,
+		Identity:           identity,
+		Tokens:             tokens,
+		Proxies:            proxies,
 		Bootstraps:         bootstraps,
 	}
 }
@@ -125,6 +294,10 @@ func displayMessages(config Config) {
 	fmt.Println("Monitoring, P2P Ports:", config.MonitoringPortFlag, config.P2pPortFlag)
 	fmt.Println("Bootstrap Nodes:", displayContacts(config.Bootstraps))
 	fmt.Println("Storage Path:", config.StorageFlag)
+	fmt.Println("Identity Path:", config.IdentityFlag)
+	fmt.Println("TLS, Mutual TLS:", config.TLSFlag, config.MTLSFlag)
+	fmt.Println("Tokens Path:", config.TokensFlag)
+	fmt.Println("Webhooks Path:", config.WebhooksFlag)
 	fmt.Println()
 }
 
@@ -134,44 +307,132 @@ func startProcess(config Config) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := settings.SetIdentity(config.Identity); err != nil {
+		log.Fatal(err)
+	}
+	peer.Identity = config.Identity
+
+	identityCertificate, err := security.Certificate(config.Identity)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool, err := security.Pool(config.TLSCAFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	peer.EnableTLS(security.ClientConfig(identityCertificate, pool))
+
+	serverCertificate := identityCertificate
+	if len(config.TLSCertFlag) > 0 {
+		serverCertificate, err = tls.LoadX509KeyPair(config.TLSCertFlag, config.TLSKeyFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	settings.SetRebalance(config.RebalanceFlag)
+	settings.SetTimeout(config.TimeoutFlag)
+	settings.SetWorkers(config.WorkersFlag)
+	settings.SetRetry(config.RetriesFlag, config.BackoffFlag)
+	settings.SetSuspicion(config.SuspicionFlag)
+	settings.SetDifficulty(config.DifficultyFlag)
+	settings.SetQueue(config.QueueFlag, config.QueueWaitFlag)
+	settings.SetFeeders(config.FeedersFlag)
+	settings.SetHops(config.HopsFlag)
+	settings.SetAcknowledgment(config.AckWaitFlag)
+	settings.SetDedup(config.DedupKeysFlag, config.DedupWindowFlag)
+	settings.SetCache(config.CacheEntriesFlag, config.CacheBytesFlag, config.CacheNegativeFlag)
+	settings.SetPush(config.PushDelayFlag, config.PollIntervalFlag)
 
 	storageInstance := mockup.NewStorage() // storage.NewStorage(config.StorageFlag)
-	node, err := core.NewNode(settings, peer.NewContact, config.Bootstraps, storageInstance)
+	node, err := core.NewNode(settings, peer.NewBinaryContact, config.Bootstraps, storageInstance)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(config.JournalFlag) > 0 {
+		journal, err := storage.OpenJournal(config.JournalFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer journal.Close()
+		if err := node.Recover(journal); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var hooks []domain.Webhook
+	var save func([]domain.Webhook) error
+	if len(config.WebhooksFlag) > 0 {
+		hooks, err = storage.LoadWebhooks(config.WebhooksFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		save = func(hooks []domain.Webhook) error { return storage.SaveWebhooks(config.WebhooksFlag, hooks) }
+	}
+	registry, err := webhook.NewRegistry(node, hooks, config.WebhookRetriesFlag, config.WebhookBackoffFlag, save, config.WebhookPrivateFlag)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer registry.Close()
 
 	monitoringHttpHandler := monitoring.NewHttpHandler(node)
-	monitoringListener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.MonitoringPortFlag))
+	monitoringHttpHandler.Hook(registry)
+	if config.Tokens != nil {
+		monitoringHttpHandler.Authorize(config.Tokens)
+	}
+	monitoringListener, err := net.Listen("tcp", net.JoinHostPort(config.MonitoringHostFlag, strconv.Itoa(config.MonitoringPortFlag)))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	p2pHttpHandler := p2p.NewHttpHandler(node, peer.NewContact)
+	p2pHttpHandler := p2p.NewHttpHandler(node, peer.NewBinaryContact, config.Identity)
+	p2pWireHandler := wire.NewHandler(node, peer.NewBinaryContact, config.Identity)
+	p2pHttpHandler.Restrict(config.DifficultyFlag)
+	p2pWireHandler.Restrict(config.DifficultyFlag)
+	if config.Tokens != nil {
+		p2pHttpHandler.Authorize(config.Tokens)
+		p2pWireHandler.Reserve()
+	}
+
+	// Budgets of two seconds absorb the bursts of the recurring jobs
+	readLimiter := domain.NewLimiter(config.ReadLimitFlag, int(2*config.ReadLimitFlag))
+	writeLimiter := domain.NewLimiter(config.WriteLimitFlag, int(2*config.WriteLimitFlag))
+	peerLimiter := domain.NewLimiter(config.PeerLimitFlag, int(2*config.PeerLimitFlag))
+	p2pHttpHandler.Limit(readLimiter, writeLimiter, peerLimiter)
+	p2pWireHandler.Limit(readLimiter, writeLimiter, peerLimiter)
+	p2pHttpHandler.Trust(config.Proxies)
 	p2pListener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.P2pPortFlag))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	var serverConfig *tls.Config
+	if config.TLSFlag {
+		serverConfig = security.ServerConfig(serverCertificate, pool)
+		monitoringListener = tls.NewListener(monitoringListener, serverConfig)
+		p2pHttpHandler.Secure(config.MTLSFlag)
+		p2pWireHandler.Secure(config.MTLSFlag)
+	}
+	p2pHttpListener, p2pWireListener := wire.Split(p2pListener, serverConfig)
+
 	workerInstance := worker.NewWorker(node)
 
-	errChan := make(chan error, 3)
-
-	go func() {
-		// errChan <- storageInstance.Start()
-	}()
-	go func() {
-		errChan <- monitoringHttpHandler.Serve(monitoringListener)
-	}()
-	go func() {
-		errChan <- p2pHttpHandler.Serve(p2pListener)
-	}()
-	go func() {
-		errChan <- workerInstance.Feed()
-	}()
-	go func() {
-		errChan <- workerInstance.Start()
-	}()
+	// Every goroutine has a slot, so that none blocks once the node stops
+	services := []func() error{
+		// storageInstance.Start,
+		func() error { return monitoringHttpHandler.Serve(monitoringListener) },
+		func() error { return p2pHttpHandler.Serve(p2pHttpListener) },
+		func() error { return p2pWireHandler.Serve(p2pWireListener) },
+		workerInstance.Feed,
+		workerInstance.Push,
+		workerInstance.Start,
+	}
+	errChan := make(chan error, len(services))
+	for _, service := range services {
+		go func(service func() error) {
+			errChan <- service()
+		}(service)
+	}
 
 	// Wait for interrupt signal to gracefully shutdown
 	signalChan := make(chan os.Signal, 5)
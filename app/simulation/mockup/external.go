@@ -1,7 +1,9 @@
 package mockup
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -21,6 +23,7 @@ type Contact struct {
 	IPs  map[string]any `json:"ips"`
 	Port int            `json:"port"`
 	IP   string         `json:"ip"`
+	Load domain.Load    `json:"load"`
 }
 
 type Handler struct {
@@ -167,7 +170,7 @@ func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
 
 	origin := h.newContact(bodyReq.Origin.Name, bodyReq.Origin.IPs, bodyReq.Origin.Port)
 
-	contact, err := node.Ping(origin)
+	contact, err := node.Ping(r.Context(), origin)
 	if err != nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
 		return
@@ -185,6 +188,7 @@ func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
 			Name: contact.Name(),
 			IPs:  contact.IPs(),
 			Port: contact.Port(),
+			Load: contact.Load(),
 		},
 	}
 	writeJSON(w, http.StatusOK, bodyResp)
@@ -205,8 +209,11 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	collection := r.URL.Query().Get("collection")
 	location := r.URL.Query().Get("location")
 
-	contact, set, err := node.Get(collection, location)
-	if err != nil {
+	contact, set, err := node.Get(r.Context(), collection, location, 0)
+	if errors.Is(err, domain.ErrInvalid) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	} else if err != nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
 		return
 	}
@@ -245,15 +252,24 @@ func (h *Handler) New(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var body struct {
-		Item    *domain.Item `json:"item"`
-		Root    string       `json:"root"`
-		Current string       `json:"current"`
+		Item    *domain.Item  `json:"item"`
+		Policy  domain.Policy `json:"policy"`
+		Root    string        `json:"root"`
+		Current string        `json:"current"`
+		Hops    []string      `json:"hops"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if err := node.New(body.Item, body.Root, body.Current); err != nil {
+	err := node.New(r.Context(), body.Item, body.Policy, body.Root, body.Current, body.Hops)
+	if errors.Is(err, domain.ErrInvalid) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	} else if errors.Is(err, domain.ErrForbidden) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	} else if err != nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
 		return
 	}
@@ -442,6 +458,7 @@ func (h *Handler) FeedNetwork(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			log.Fatal(err)
 		}
+		settings.SetRebalance(2)
 
 		node, err := core.NewNode(settings, NewContact, bootstraps, NewStorage())
 		if err != nil {
@@ -453,6 +470,10 @@ func (h *Handler) FeedNetwork(w http.ResponseWriter, r *http.Request) {
 			defer workerInstance.Close()
 			h.errChan <- workerInstance.Feed()
 		}()
+		go func() {
+			defer workerInstance.Close()
+			h.errChan <- workerInstance.Push()
+		}()
 		go func() {
 			defer workerInstance.Close()
 			h.errChan <- workerInstance.Start()
@@ -482,6 +503,11 @@ func (h *Handler) FeedCollection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optional policy of the collection, defaults of the nodes apply otherwise
+	policy := domain.Policy{}
+	policy.Delegation, _ = strconv.Atoi(r.URL.Query().Get("delegation"))
+	policy.SetLength, _ = strconv.Atoi(r.URL.Query().Get("setLength"))
+
 	go func() {
 		for i := 0; i < c; i++ {
 			item := &domain.Item{
@@ -489,7 +515,7 @@ func (h *Handler) FeedCollection(w http.ResponseWriter, r *http.Request) {
 				Location:   domain.EncodeId(domain.RandomId()),
 				Id:         fmt.Sprintf("%d", i),
 			}
-			err := network.Random().New(item, domain.Root(), item.Location)
+			err := network.Random().New(context.Background(), item, policy, domain.Root(), item.Location, nil)
 			if err != nil {
 				log.Println(err)
 			}
@@ -1,6 +1,8 @@
 package mockup
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 
@@ -52,6 +54,7 @@ type Peer struct {
 	ips  map[string]any
 	port int
 	ip   string
+	load domain.Load
 }
 
 func NewContact(name string, ips map[string]any, port int) domain.Contact {
@@ -90,29 +93,35 @@ func (p *Peer) Host() string {
 	return fmt.Sprintf("%s@%s|%d", p.name, p.ip, p.port)
 }
 
-func (p *Peer) Ping(origin domain.Contact) (domain.Contact, error) {
+func (p *Peer) Load() domain.Load {
+	return p.load
+}
+
+func (p *Peer) Ping(ctx context.Context, origin domain.Contact) (domain.Contact, error) {
 
 	distant, ok := network.nodes[p.Name()]
 	if !ok {
 		return nil, fmt.Errorf("error code: 404")
 	}
 
-	node, err := distant.Ping(NewContact(origin.Name(), origin.IPs(), origin.Port()))
+	node, err := distant.Ping(ctx, NewContact(origin.Name(), origin.IPs(), origin.Port()))
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %s", err.Error())
 	}
 
-	return NewContact(node.Name(), node.IPs(), node.Port()), nil
+	p.load = node.Load()
+
+	return &Peer{name: node.Name(), ips: node.IPs(), port: node.Port(), load: node.Load()}, nil
 }
 
-func (p *Peer) Neighbors(origin domain.Peer) ([]domain.Contact, error) {
+func (p *Peer) Neighbors(ctx context.Context, origin domain.Peer) ([]domain.Contact, error) {
 
 	distant, ok := network.nodes[p.Name()]
 	if !ok {
 		return nil, fmt.Errorf("error code: 404")
 	}
 
-	neighbors, err := distant.Neighbors(origin)
+	neighbors, err := distant.Neighbors(ctx, origin)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %s", err.Error())
 	}
@@ -125,14 +134,14 @@ func (p *Peer) Neighbors(origin domain.Peer) ([]domain.Contact, error) {
 	return contacts, nil
 }
 
-func (p *Peer) Random(origin domain.Peer) (domain.Contact, error) {
+func (p *Peer) Random(ctx context.Context, origin domain.Peer) (domain.Contact, error) {
 
 	distant, ok := network.nodes[p.Name()]
 	if !ok {
 		return nil, fmt.Errorf("error code: 404")
 	}
 
-	random, err := distant.Random(origin)
+	random, err := distant.Random(ctx, origin)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %s", err.Error())
 	}
@@ -144,14 +153,29 @@ func (p *Peer) Random(origin domain.Peer) (domain.Contact, error) {
 	return NewContact(random.Name(), random.IPs(), random.Port()), nil
 }
 
-func (p *Peer) Transfer(origin domain.Peer, key domain.Key, items []*domain.Item) error {
+func (p *Peer) Transfer(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+
+	distant, ok := network.nodes[p.Name()]
+	if !ok {
+		return fmt.Errorf("error code: 404")
+	}
+
+	err := distant.Transfer(ctx, origin, key, policy, items, witnesses, redirects)
+	if err != nil {
+		return fmt.Errorf("error making request: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (p *Peer) Adopt(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
 
 	distant, ok := network.nodes[p.Name()]
 	if !ok {
 		return fmt.Errorf("error code: 404")
 	}
 
-	err := distant.Transfer(origin, key, items)
+	err := distant.Adopt(ctx, origin, key, policy, items, witnesses, redirects)
 	if err != nil {
 		return fmt.Errorf("error making request: %s", err.Error())
 	}
@@ -159,14 +183,17 @@ func (p *Peer) Transfer(origin domain.Peer, key domain.Key, items []*domain.Item
 	return nil
 }
 
-func (p *Peer) Get(collection string, location string) (domain.Contact, *domain.Set, error) {
+func (p *Peer) Get(ctx context.Context, collection string, location string, version uint64) (domain.Contact, *domain.Set, error) {
 
 	distant, ok := network.nodes[p.Name()]
 	if !ok {
 		return nil, nil, fmt.Errorf("error code: 404")
 	}
 
-	contact, set, err := distant.Get(collection, location)
+	contact, set, err := distant.Get(ctx, collection, location, version)
+	if errors.Is(err, domain.ErrNotModified) {
+		return contact, nil, err
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("error making request: %s", err.Error())
 	}
@@ -174,17 +201,92 @@ func (p *Peer) Get(collection string, location string) (domain.Contact, *domain.
 	return contact, set, nil
 }
 
-func (p *Peer) New(item *domain.Item, root string, current string) error {
+func (p *Peer) New(ctx context.Context, item *domain.Item, policy domain.Policy, root string, current string, hops []string) error {
 
 	distant, ok := network.nodes[p.Name()]
 	if !ok {
 		return fmt.Errorf("error code: 404")
 	}
 
-	err := distant.New(item, root, current)
+	err := distant.New(ctx, item, policy, root, current, hops)
 	if err != nil {
 		return fmt.Errorf("error making request: %s", err.Error())
 	}
 
 	return err
 }
+
+func (p *Peer) Insert(ctx context.Context, item *domain.Item, policy domain.Policy, root string, current string, hops []string) (domain.Contact, string, error) {
+
+	distant, ok := network.nodes[p.Name()]
+	if !ok {
+		return nil, "", fmt.Errorf("error code: 404")
+	}
+
+	contact, location, err := distant.Insert(ctx, item, policy, root, current, hops)
+	if err != nil {
+		return nil, "", domain.KindError(domain.Kind(err), err.Error())
+	}
+
+	return NewContact(contact.Name(), contact.IPs(), contact.Port()), location, nil
+}
+
+func (p *Peer) Batch(ctx context.Context, insertions []*domain.Insertion) ([]error, error) {
+
+	distant, ok := network.nodes[p.Name()]
+	if !ok {
+		return nil, fmt.Errorf("error code: 404")
+	}
+
+	results, err := distant.Batch(ctx, insertions)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %s", err.Error())
+	}
+
+	// Only the kind and the message of the errors go through the network
+	for i, err := range results {
+		if err != nil {
+			results[i] = domain.KindError(domain.Kind(err), err.Error())
+		}
+	}
+
+	return results, nil
+}
+
+func (p *Peer) Changes(ctx context.Context, collection string, location string, version uint64) (domain.Contact, *domain.Delta, error) {
+
+	distant, ok := network.nodes[p.Name()]
+	if !ok {
+		return nil, nil, fmt.Errorf("error code: 404")
+	}
+
+	contact, delta, err := distant.Changes(ctx, collection, location, version)
+	if errors.Is(err, domain.ErrNotModified) {
+		return contact, nil, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error making request: %s", err.Error())
+	}
+
+	return contact, delta, nil
+}
+
+func (p *Peer) Invalidate(ctx context.Context, origin domain.Peer, key domain.Key, set *domain.Set) error {
+
+	distant, ok := network.nodes[p.Name()]
+	if !ok {
+		return fmt.Errorf("error code: 404")
+	}
+
+	return distant.Invalidate(ctx, origin, key, set)
+}
+
+func (p *Peer) Subscribe(ctx context.Context, collection string, location string, hops []string) (<-chan domain.Event, error) {
+
+	distant, ok := network.nodes[p.Name()]
+	if !ok {
+		return nil, fmt.Errorf("error code: 404")
+	}
+
+	return distant.Subscribe(ctx, collection, location, hops)
+}
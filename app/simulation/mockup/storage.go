@@ -29,6 +29,10 @@ func (s *Storage) Load() ([]string, error) {
 func (s *Storage) Append(log string) {
 }
 
+func (s *Storage) Commit(log string) error {
+	return nil
+}
+
 func (s *Storage) Stream(start int) <-chan string {
 	stream := make(chan string)
 	go func() {
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"log"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// measure refreshes the load figures published with Ping.
+func (n *Node) measure() {
+	count, _ := n.Count()
+	n.items.Store(int64(count))
+	n.meter.Tick()
+}
+
+// rebalance hands the hottest sub-area of the node to the least loaded peer
+// of the routing table, if the load score of the node exceeds its score by
+// the rebalance factor. Once started, it goes on until the score falls below
+// half way between, so that loads close to the factor do not flap.
+func (n *Node) rebalance(ctx context.Context) {
+	if n.settings.rebalance <= 0 {
+		return
+	}
+
+	load := n.Load()
+	factor := n.settings.rebalance
+	if n.balancing.Load() {
+		factor = (1 + factor) / 2
+	}
+
+	var target domain.Contact
+	for _, candidate := range n.traverseRouting(false) {
+		if !load.Exceeds(candidate.Load(), factor) {
+			continue
+		}
+		if target == nil || candidate.Load().Score() < target.Load().Score() {
+			target = candidate
+		}
+	}
+	n.balancing.Store(target != nil)
+	if target == nil {
+		return
+	}
+
+	// The moved area must not make the target busier than the node
+	key, found := n.hottest(int(load.Score()-target.Load().Score()) / 2)
+	if !found {
+		return
+	}
+
+	if err := n.handover(ctx, target, key); err != nil {
+		log.Printf("Error handing %s:%s over to %s: %v", key.Collection, key.Location, target.Name(), err)
+	}
+}
+
+// hottest returns the sub-area of an owned area holding the most items, up to max.
+func (n *Node) hottest(max int) (domain.Key, bool) {
+	result, found, best := domain.Key{}, false, 1
+
+	n.owned.Traverse(0, make([]byte, domain.IdLength()), func(i int, b []byte, keys map[domain.Key]any) {
+		for key := range keys {
+			collection, exist := n.collections.Get(key.Collection)
+			if !exist {
+				continue
+			}
+			set, exist := collection.Get(key.Location)
+			if !exist {
+				continue
+			}
+			for child, count := range set.List() {
+				if count <= best || count > max || !collection.Browsable(key.Location, child) {
+					continue
+				}
+				if _, exist := collection.Get(child); !exist {
+					continue
+				}
+				result, found, best = domain.Key{Collection: key.Collection, Location: child}, true, count
+			}
+		}
+	})
+
+	return result, found
+}
+
+// handover moves an area to the target, with the redirects of the areas
+// below it, and redirects the node to it.
+func (n *Node) handover(ctx context.Context, target domain.Contact, key domain.Key) error {
+
+	collection, exist := n.collections.Get(key.Collection)
+	if !exist {
+		return nil
+	}
+
+	sent := make(map[string]any)
+	items := collection.Items(key.Location)
+	for _, item := range items {
+		sent[item.Content()] = nil
+	}
+	redirects := n.redirects.Below(key)
+
+	err := n.once(ctx, target, func(ctx context.Context) error {
+		return target.Adopt(ctx, n, key, collection.Policy(), items, n.witnesses(key), redirects)
+	})
+	if err != nil {
+		return err
+	}
+
+	n.redirects.Set(key, target)
+	for _, redirect := range redirects {
+		n.redirects.Remove(redirect.Key, redirect.Contact)
+	}
+
+	remaining, empty := collection.Delegate(key.Location)
+	if empty {
+		n.collections.Delete(key.Collection)
+	}
+	n.publish(domain.Removed, key.Location, target.Name(), remaining...)
+
+	// Items added since the traversal are queued again, following the redirect
+	for _, item := range remaining {
+		if _, exist := sent[item.Content()]; exist {
+			continue
+		}
+		if err := n.receive(item, collection.Policy(), key.Location, item.Location); err != nil {
+			log.Printf("Error queueing %s:%s for %s: %v", item.Collection, item.Id, target.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// move hands the redirect of an area to the peer now nearest to it.
+type move struct {
+	candidate domain.Contact
+	redirect  domain.Redirect
+}
+
+// relocate returns the redirects of the areas handed to peers which the node
+// is no longer the nearest to, so that they follow the areas to the peer
+// resolving them next.
+func (n *Node) relocate() []move {
+	moves := make([]move, 0)
+	for key, contact := range n.redirects.List() {
+		// Adopted areas stay on the node
+		if contact.Name() == n.Name() {
+			continue
+		}
+		id, err := domain.DecodeLocation(key.Collection, key.Location)
+		if err != nil {
+			continue
+		}
+		if nearest := n.registered.Nearest(0, id); nearest != nil && nearest.Name() != n.Name() {
+			moves = append(moves, move{candidate: nearest, redirect: domain.Redirect{Key: key, Contact: contact}})
+		}
+	}
+	return moves
+}
+
+// reclaim takes back the areas handed to a peer which left the network.
+func (n *Node) reclaim(peer domain.Peer) {
+	for _, key := range n.redirects.Forget(peer) {
+		if collection, exist := n.collections.Get(key.Collection); exist {
+			n.create(key.Collection, key.Location, collection.Policy())
+		}
+	}
+}
+
+// restore takes back the items of an area whose transfer failed, the area
+// being transferred again by the next refresh. The items are already in the
+// log of the node.
+func (n *Node) restore(key domain.Key, policy domain.Policy, items []*domain.Item) {
+	n.create(key.Collection, key.Location, policy)
+
+	collection, exist := n.collections.Get(key.Collection)
+	if !exist {
+		return
+	}
+	for _, item := range items {
+		if areas := collection.Add(item); len(areas) > 0 {
+			n.own(collection, areas)
+		}
+	}
+	n.publish(domain.Added, key.Location, "", items...)
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// adopter is a peer adopting every area, recording the redirects handed over.
+type adopter struct {
+	stub
+	redirects []domain.Redirect
+}
+
+func (a *adopter) Adopt(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	a.redirects = redirects
+	return nil
+}
+
+func TestHandoverMovesTheRedirectsBelowTheArea(t *testing.T) {
+	n := newTestNode(t)
+	origin, other := &stub{name: name(t)}, &stub{name: name(t)}
+	n.register([]domain.Contact{origin})
+	ctx := context.Background()
+
+	key := domain.Key{Collection: name(t), Location: domain.Root()}
+	below := domain.Key{Collection: key.Collection, Location: "A"}
+	outside := domain.Key{Collection: name(t), Location: "A"}
+	redirects := []domain.Redirect{{Key: below, Contact: other}, {Key: outside, Contact: other}}
+
+	if err := n.Adopt(ctx, origin, key, domain.Policy{}, nil, nil, redirects); err != nil {
+		t.Fatal(err)
+	}
+	if contact, exist := n.redirects.Get(below); !exist || contact.Name() != other.Name() {
+		t.Errorf("redirect handed over with the area resolves to %v, %t", contact, exist)
+	}
+	if _, exist := n.redirects.Get(outside); exist {
+		t.Error("redirect of another collection taken")
+	}
+
+	target := &adopter{stub: stub{name: name(t)}}
+	if err := n.handover(ctx, target, key); err != nil {
+		t.Fatal(err)
+	}
+	if len(target.redirects) != 1 || target.redirects[0].Key != below {
+		t.Errorf("target adopted the redirects %+v, want %v", target.redirects, below)
+	}
+	if _, exist := n.redirects.Get(below); exist {
+		t.Error("redirect handed over still on the node")
+	}
+	if contact, _ := n.redirects.Get(key); contact.Name() != target.Name() {
+		t.Errorf("area redirected to %s, want %s", contact.Name(), target.Name())
+	}
+	if _, exist := n.collections.Get(key.Collection); exist {
+		t.Error("collection emptied by the handover still held")
+	}
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// Batch queues the insertions of the areas of the node and forwards the
+// others to the nodes of their areas, in one call per node. It returns the
+// result of every insertion.
+func (n *Node) Batch(ctx context.Context, insertions []*domain.Insertion) ([]error, error) {
+	results := make([]error, len(insertions))
+
+	contacts := make(map[string]domain.Contact)
+	groups := make(map[string][]int)
+	for i, insertion := range insertions {
+		if err := validate(insertion.Item, insertion.Root, insertion.Current); err != nil {
+			results[i] = err
+			continue
+		}
+
+		contact, err := n.find(insertion.Item.Collection, insertion.Current)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+
+		if contact.Name() == n.Name() {
+			results[i] = n.New(ctx, insertion.Item, insertion.Policy, insertion.Root, insertion.Current, insertion.Hops)
+			continue
+		}
+		if len(insertion.Hops) >= n.settings.hops {
+			results[i] = domain.ErrHops
+			continue
+		}
+
+		contacts[contact.Name()] = contact
+		groups[contact.Name()] = append(groups[contact.Name()], i)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+
+	fanout(n.settings.workers, names, func(name string) {
+		contact, indexes := contacts[name], groups[name]
+
+		batch := make([]*domain.Insertion, 0, len(indexes))
+		for _, i := range indexes {
+			forwarded := *insertions[i]
+			forwarded.Hops = append(slices.Clip(forwarded.Hops), n.Name())
+			batch = append(batch, &forwarded)
+		}
+
+		// A batch is not retried, as its items would be queued twice
+		var remote []error
+		err := n.once(ctx, contact, func(ctx context.Context) error {
+			var err error
+			remote, err = contact.Batch(ctx, batch)
+			return err
+		})
+
+		for j, i := range indexes {
+			switch {
+			case err != nil:
+				results[i] = err
+			case j < len(remote):
+				results[i] = remote[j]
+			default:
+				results[i] = fmt.Errorf("no result from %s", name)
+			}
+		}
+	})
+
+	return results, nil
+}
@@ -1,17 +1,91 @@
 package core
 
-import "github.com/indexus/go-indexus-core/domain"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
 
 type Element struct {
-	item    *domain.Item
-	root    string
-	current string
+	item     *domain.Item
+	policy   domain.Policy
+	root     string
+	current  string
+	hops     []string
+	seq      uint64
+	attempts int
+	// reason is why the element was given up, empty while it is processed
+	reason string
+	failed time.Time
+	// ack receives the receipt of the insertion until the deadline
+	ack      chan receipt
+	deadline time.Time
+}
+
+// receipt tells the node and the area an element was added to.
+type receipt struct {
+	contact  domain.Contact
+	location string
+	err      error
 }
 
-func NewElement(item *domain.Item, root, current string) *Element {
+func NewElement(item *domain.Item, policy domain.Policy, root, current string, hops []string) *Element {
 	return &Element{
 		item:    item,
+		policy:  policy,
 		root:    root,
 		current: current,
+		hops:    hops,
+	}
+}
+
+// waited reports whether the receipt of the element is still waited for.
+func (e *Element) waited() bool {
+	return e.ack != nil && time.Now().Before(e.deadline)
+}
+
+// acknowledge sends the receipt of the element to the caller waiting for it.
+func (e *Element) acknowledge(contact domain.Contact, location string, err error) {
+	if e.ack == nil {
+		return
+	}
+	select {
+	case e.ack <- receipt{contact: contact, location: location, err: err}:
+	default:
+	}
+}
+
+// entry is an element as written in the journal.
+type entry struct {
+	Item    *domain.Item  `json:"item"`
+	Policy  domain.Policy `json:"policy"`
+	Root    string        `json:"root"`
+	Current string        `json:"current"`
+	Hops    []string      `json:"hops,omitempty"`
+	// A letter, given up, has a reason
+	Attempts int        `json:"attempts,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+	Failed   *time.Time `json:"failed,omitempty"`
+}
+
+func (e *Element) MarshalJSON() ([]byte, error) {
+	aux := &entry{Item: e.item, Policy: e.policy, Root: e.root, Current: e.current, Hops: e.hops}
+	if len(e.reason) > 0 {
+		aux.Attempts, aux.Reason, aux.Failed = e.attempts, e.reason, &e.failed
+	}
+	return json.Marshal(aux)
+}
+
+func (e *Element) UnmarshalJSON(data []byte) error {
+	var aux entry
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	e.item, e.policy, e.root, e.current, e.hops = aux.Item, aux.Policy, aux.Root, aux.Current, aux.Hops
+	e.attempts, e.reason = aux.Attempts, aux.Reason
+	if aux.Failed != nil {
+		e.failed = *aux.Failed
 	}
+	return nil
 }
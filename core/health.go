@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+var ErrCircuitOpen = errors.New("circuit open")
+
+// call runs an idempotent request to the peer, such as Ping, Neighbors or Get,
+// retrying with an exponential backoff, unless the circuit of the peer is
+// open. Every attempt is bounded by the timeout of the node.
+func (n *Node) call(ctx context.Context, contact domain.Contact, request func(context.Context) error) error {
+	return n.attempt(ctx, contact, n.settings.attempts, request)
+}
+
+// once runs a request to the peer a single time, for the requests which must
+// not be applied twice, such as New, Transfer, Adopt or Batch.
+func (n *Node) once(ctx context.Context, contact domain.Contact, request func(context.Context) error) error {
+	return n.attempt(ctx, contact, 1, request)
+}
+
+// probe runs an idempotent request to the peer like call even when its
+// circuit is open, for the pings telling whether it recovered.
+func (n *Node) probe(ctx context.Context, contact domain.Contact, request func(context.Context) error) error {
+	return n.retry(ctx, contact, n.settings.attempts, request)
+}
+
+func (n *Node) attempt(ctx context.Context, contact domain.Contact, attempts int, request func(context.Context) error) error {
+	if !n.health.Allow(contact) {
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, contact.Name())
+	}
+	return n.retry(ctx, contact, attempts, request)
+}
+
+// retry runs the request up to attempts times. Only the failures to reach the
+// peer count against its health: a peer answering with an error, such as a
+// refused item or backpressure, is reachable. Backpressure is retried, other
+// answers are final.
+func (n *Node) retry(ctx context.Context, contact domain.Contact, attempts int, request func(context.Context) error) error {
+
+	var err error
+	backoff := n.settings.backoff
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				n.record(contact, err)
+				return err
+			}
+			backoff *= 2
+		}
+
+		callCtx, cancel := n.deadline(ctx)
+		err = request(callCtx)
+		cancel()
+
+		if err == nil || (answered(err) && !busy(err)) {
+			break
+		}
+	}
+
+	n.record(contact, err)
+	return err
+}
+
+// record counts the result of a call in the health of the peer.
+func (n *Node) record(contact domain.Contact, err error) {
+	if err == nil || answered(err) {
+		n.health.Success(contact)
+	} else {
+		n.health.Failure(contact, err)
+	}
+}
+
+// answered reports whether the peer was reached and answered with the error.
+func answered(err error) bool {
+	return domain.Answered(err) || errors.Is(err, domain.ErrPending) || errors.Is(err, domain.ErrNotModified)
+}
+
+// busy reports whether the peer refused the request until it has room.
+func busy(err error) bool {
+	return errors.Is(err, domain.ErrFull) || errors.Is(err, domain.ErrRateLimited)
+}
+
+func (n *Node) Health() map[string]domain.Record {
+	return n.health.List()
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// stub is a peer answering the calls with the errors of the test.
+type stub struct {
+	domain.Contact
+	name string
+}
+
+func (s *stub) Name() string {
+	return s.name
+}
+
+func (s *stub) ID() []byte {
+	id, _ := domain.DecodeName(s.name)
+	return id
+}
+
+func testNode() *Node {
+	return &Node{
+		settings: &Settings{attempts: 3, backoff: time.Millisecond, timeout: time.Second},
+		health:   domain.NewHealth(2, time.Minute),
+	}
+}
+
+func TestHealthIgnoresTheErrorsAnsweredByAPeer(t *testing.T) {
+	n, peer := testNode(), &stub{name: "peer"}
+
+	for _, kind := range []string{"forbidden", "invalid", "unauthorized", "error"} {
+		calls := 0
+		err := n.call(context.Background(), peer, func(context.Context) error {
+			calls++
+			return domain.KindError(kind, kind)
+		})
+		if !domain.Answered(err) || calls != 1 {
+			t.Errorf("%s answered %v after %d calls, want one call", kind, err, calls)
+		}
+	}
+	if !n.health.Allow(peer) || n.health.Dead(peer) {
+		t.Errorf("circuit of a peer answering errors is open: %+v", n.Health())
+	}
+	if retryable(domain.KindError("forbidden", "refused")) {
+		t.Error("forbidden item answered by a peer is retried")
+	}
+}
+
+func TestHealthRetriesBackpressure(t *testing.T) {
+	n, peer := testNode(), &stub{name: "peer"}
+
+	calls := 0
+	err := n.call(context.Background(), peer, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return domain.KindError("full", "queue full")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("answered %v after %d calls, want success after 3", err, calls)
+	}
+	if !n.health.Allow(peer) {
+		t.Error("circuit of a busy peer is open")
+	}
+}
+
+func TestHealthOpensTheCircuitOnTransportFailures(t *testing.T) {
+	n, peer := testNode(), &stub{name: "peer"}
+	unreachable := errors.New("connection refused")
+
+	if err := n.once(context.Background(), peer, func(context.Context) error { return unreachable }); err != unreachable {
+		t.Fatalf("answered %v, want %v", err, unreachable)
+	}
+	if err := n.once(context.Background(), peer, func(context.Context) error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call through an open circuit answered %v", err)
+	}
+
+	// A probe goes through the open circuit and closes it once the peer answers
+	if err := n.probe(context.Background(), peer, func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if !n.health.Allow(peer) || len(n.Health()) != 0 {
+		t.Errorf("circuit of a recovered peer is still open: %+v", n.Health())
+	}
+}
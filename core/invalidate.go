@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// Watch records that the peer fetched a set owned by the node, which is then
+// pushed to it whenever it changes.
+func (n *Node) Watch(origin domain.Peer, key domain.Key) {
+	if origin.Name() == n.Name() {
+		return
+	}
+	collection, exist := n.collections.Get(key.Collection)
+	if !exist {
+		return
+	}
+	if _, owned := collection.Get(key.Location); owned {
+		n.watchers.Watch(key, origin.Name())
+	}
+}
+
+// Invalidate replaces the cached set of the key by the set pushed by its
+// owner, nil dropping it until it is fetched again.
+func (n *Node) Invalidate(ctx context.Context, origin domain.Peer, key domain.Key, set *domain.Set) error {
+	if err := domain.ValidateKey(key); err != nil {
+		return err
+	}
+
+	// Anyone may drop a set, which is only fetched again, but only the owner
+	// may replace it
+	if set != nil {
+		owner, err := n.find(key.Collection, key.Location)
+		if err != nil {
+			return err
+		}
+		if owner.Name() != origin.Name() {
+			return fmt.Errorf("%w: %s does not own %s:%s", domain.ErrForbidden, origin.Name(), key.Collection, key.Location)
+		}
+	}
+
+	n.cache.Push(key.Collection, key.Location, set)
+
+	if c, exist := n.collections.Get(key.Collection); exist && set != nil {
+		if c.Update(domain.Parent(key.Location), key.Location, set.Count()) {
+			n.watchers.Touch(key.Collection, key.Location)
+		}
+	}
+	return nil
+}
+
+// Push sends the sets of the node changed since the last push to the peers
+// caching them, the changes being gathered for the push delay. A peer failing
+// a push is forgotten and polls the set again.
+func (n *Node) Push() error {
+	for {
+		time.Sleep(n.settings.push)
+
+		changed := n.watchers.Drain()
+		keys := make([]domain.Key, 0, len(changed))
+		for key := range changed {
+			keys = append(keys, key)
+		}
+
+		fanout(n.settings.workers, keys, func(key domain.Key) {
+			set := n.snapshot(key)
+			for _, name := range changed[key] {
+				if err := n.push(name, key, set); err != nil {
+					log.Printf("Error pushing %s:%s to %s: %v", key.Collection, key.Location, name, err)
+					n.watchers.Forget(key, name)
+				}
+			}
+		})
+	}
+}
+
+func (n *Node) push(name string, key domain.Key, set *domain.Set) error {
+	id, err := domain.DecodeName(name)
+	if err != nil {
+		return err
+	}
+	contact, exist := n.registered.Get(0, id)
+	if !exist || contact == nil || contact.Name() != name {
+		return fmt.Errorf("peer %s is not registered", name)
+	}
+
+	return n.call(context.Background(), contact, func(ctx context.Context) error {
+		return contact.Invalidate(ctx, n, key, set)
+	})
+}
+
+// snapshot returns a copy of the set of the key if the node still owns it, nil
+// otherwise.
+func (n *Node) snapshot(key domain.Key) *domain.Set {
+	collection, exist := n.collections.Get(key.Collection)
+	if !exist {
+		return nil
+	}
+	set, owned := collection.Get(key.Location)
+	if !owned {
+		return nil
+	}
+	return set.Copy()
+}
@@ -1,41 +1,60 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/indexus/go-indexus-core/domain"
 )
 
-func (n *Node) Observe() error {
+func (n *Node) Observe(ctx context.Context) error {
 
+	mu := &sync.Mutex{}
 	toIgnore := make([]domain.Contact, 0)
 	toRegister := make([]domain.Contact, 0)
 	toReject := make([]domain.Contact, 0)
 
-	empty := true
+	registered := n.traverseRegistered(false)
+	acknowledged := n.traverseAcknowledged(false)
 
-	for _, contact := range n.traverseRegistered(false) {
-		empty = false
+	fanout(n.settings.workers, registered, func(contact domain.Contact) {
+		// The ping goes through an open circuit, a recovered peer closing it
+		n.probe(ctx, contact, func(ctx context.Context) error {
+			_, err := contact.Ping(ctx, n)
+			return err
+		})
 
-		_, err := contact.Ping(n)
-		if err != nil {
+		// Suspected peers are kept until their failures reach the threshold
+		if n.health.Dead(contact) {
+			mu.Lock()
 			toReject = append(toReject, contact)
+			mu.Unlock()
 		}
-	}
-	for _, contact := range n.traverseAcknowledged(false) {
-		empty = false
+	})
+	fanout(n.settings.workers, acknowledged, func(contact domain.Contact) {
+		ctx, cancel := n.deadline(ctx)
+		defer cancel()
+
+		node, err := contact.Ping(ctx, n)
+
+		mu.Lock()
+		defer mu.Unlock()
 
-		node, err := contact.Ping(n)
 		if node != nil {
 			toRegister = append(toRegister, node)
 		}
 		if err != nil || node.Name() != contact.Name() {
 			toIgnore = append(toIgnore, contact)
 		}
-	}
+	})
 
-	if empty {
-		n.acknowledge(n.bootstraps)
+	if len(registered) == 0 && len(acknowledged) == 0 {
+		n.bootstrap()
 	}
 
 	n.ignore(toIgnore)
@@ -45,32 +64,73 @@ func (n *Node) Observe() error {
 	return nil
 }
 
-func (n *Node) Refresh() error {
+func (n *Node) Refresh(ctx context.Context) error {
 
+	mu := &sync.Mutex{}
 	toRegister := make([]domain.Contact, 0)
 
-	for _, contact := range n.traverseRouting(false) {
-		contacts, err := contact.Neighbors(n)
+	fanout(n.settings.workers, n.traverseRouting(false), func(contact domain.Contact) {
+		var contacts []domain.Contact
+		err := n.call(ctx, contact, func(ctx context.Context) (err error) {
+			contacts, err = contact.Neighbors(ctx, n)
+			return err
+		})
 		if err != nil {
-			continue
+			return
 		}
+
+		mu.Lock()
 		toRegister = append(toRegister, contacts...)
-	}
+		mu.Unlock()
+	})
 
 	n.register(toRegister)
 
-	for candidate, keys := range n.control() {
+	type transfer struct {
+		candidate domain.Contact
+		key       domain.Key
+		items     []*domain.Item
+	}
+
+	transfers := make([]transfer, 0)
+	transferable, policies := n.control()
+	for candidate, keys := range transferable {
 		for key, items := range keys {
-			candidate.Transfer(n, key, items)
+			transfers = append(transfers, transfer{candidate, key, items})
 		}
 	}
 
+	fanout(n.settings.workers, transfers, func(t transfer) {
+		err := n.once(ctx, t.candidate, func(ctx context.Context) error {
+			return t.candidate.Transfer(ctx, n, t.key, policies[t.key.Collection], t.items, n.witnesses(t.key), nil)
+		})
+		if err != nil {
+			log.Printf("Error transferring %s:%s to %s: %v", t.key.Collection, t.key.Location, t.candidate.Name(), err)
+			n.restore(t.key, policies[t.key.Collection], t.items)
+		}
+	})
+
+	// The redirects follow their areas to the peers now nearest to them
+	fanout(n.settings.workers, n.relocate(), func(m move) {
+		err := n.once(ctx, m.candidate, func(ctx context.Context) error {
+			return m.candidate.Transfer(ctx, n, m.redirect.Key, domain.Policy{}, nil, nil, []domain.Redirect{m.redirect})
+		})
+		if err != nil {
+			log.Printf("Error handing the redirect of %s:%s over to %s: %v", m.redirect.Key.Collection, m.redirect.Key.Location, m.candidate.Name(), err)
+			return
+		}
+		n.redirects.Remove(m.redirect.Key, m.redirect.Contact)
+	})
+
+	n.measure()
+	n.rebalance(ctx)
+
 	err := n.storage.Save(n.Snapshot())
 	if err != nil {
 		return err
 	}
 
-	err = n.clean()
+	err = n.clean(ctx)
 	if err != nil {
 		return err
 	}
@@ -78,12 +138,16 @@ func (n *Node) Refresh() error {
 	return nil
 }
 
-func (n *Node) Update() error {
+func (n *Node) Update(ctx context.Context) error {
 
-	refresh := n.cache.Refresh(n.settings.expiration)
+	refresh := n.cache.Refresh()
+	n.watchers.Expire()
 
 	for _, collection := range n.collections.List() {
 		for location := range collection.Refresh() {
+			if n.cache.Fresh(collection.Name(), location) {
+				continue
+			}
 			if _, exist := refresh[collection.Name()]; !exist {
 				refresh[collection.Name()] = make(map[string]any)
 			}
@@ -91,43 +155,168 @@ func (n *Node) Update() error {
 		}
 	}
 
+	keys := make([]domain.Key, 0)
 	for collection, sets := range refresh {
-
 		for location := range sets {
+			keys = append(keys, domain.Key{Collection: collection, Location: location})
+		}
+	}
 
-			contact, err := n.find(collection, location)
-			if err != nil {
-				log.Println(err)
-			}
+	fanout(n.settings.workers, keys, func(key domain.Key) {
 
-			_, set, err := contact.Get(collection, location)
-			if err != nil {
-				log.Println(err)
-			}
+		contact, err := n.find(key.Collection, key.Location)
+		if err != nil {
+			log.Println(err)
+			return
+		}
 
-			if set == nil {
-				continue
+		var delta *domain.Delta
+		modified := true
+		err = n.call(ctx, contact, func(ctx context.Context) (err error) {
+			_, delta, err = contact.Changes(ctx, key.Collection, key.Location, n.cache.Version(key.Collection, key.Location))
+			if errors.Is(err, domain.ErrNotModified) {
+				modified, err = false, nil
 			}
+			return err
+		})
+		if err != nil {
+			log.Println(err)
+		}
+
+		if !modified {
+			n.cache.Unchanged(key.Collection, key.Location)
+			return
+		}
+		if delta == nil {
+			return
+		}
 
-			n.cache.Set(collection, location, set)
+		set := n.cache.Apply(key.Collection, key.Location, delta)
+		if set == nil {
+			return
+		}
 
-			if c, exist := n.collections.Get(collection); exist {
-				c.Update(domain.Parent(location), location, set.Count())
+		if c, exist := n.collections.Get(key.Collection); exist {
+			if c.Update(domain.Parent(key.Location), key.Location, set.Count()) {
+				n.watchers.Touch(key.Collection, key.Location)
 			}
 		}
-	}
+	})
 	return nil
 }
 
+// Feed inserts the queued items, the collections being dispatched to the
+// feeders so that the items of a collection keep their order.
 func (n *Node) Feed() error {
+	shards := make([]chan *Element, n.settings.feeders)
+	for i := range shards {
+		shards[i] = make(chan *Element, 64)
+		go func(shard chan *Element) {
+			for element := range shard {
+				n.process(element)
+			}
+		}(shards[i])
+	}
+
 	for {
 		element, exist := n.queue.Consume()
 		if !exist {
 			continue
 		}
-		err := n.insert(element.item, element.root, element.current)
-		if err != nil {
-			return err
+		h := fnv.New32a()
+		h.Write([]byte(element.item.Collection))
+		shards[h.Sum32()%uint32(len(shards))] <- element
+	}
+}
+
+// process inserts the element, which is queued again with backoff when it
+// fails until it runs out of attempts.
+func (n *Node) process(element *Element) {
+	contact, location, err := n.feed(element)
+	if !errors.Is(err, errForwarding) {
+		n.settle(element, contact, location, err)
+	}
+}
+
+// errForwarding tells that the element is being forwarded to the node of its
+// area, which settles it once done.
+var errForwarding = errors.New("forwarding")
+
+// settle acknowledges the element inserted, or queues it again with backoff
+// when it failed until it runs out of attempts.
+func (n *Node) settle(element *Element, contact domain.Contact, location string, err error) {
+	if err == nil || errors.Is(err, domain.ErrPending) {
+		if contact != nil || err != nil {
+			element.acknowledge(contact, location, err)
 		}
+		n.done(element)
+		return
 	}
+
+	element.attempts++
+	if element.attempts < n.settings.attempts && retryable(err) {
+		time.AfterFunc(n.settings.backoff<<(element.attempts-1), func() {
+			n.queue.Put(element)
+		})
+		return
+	}
+
+	element.reason, element.failed = err.Error(), time.Now()
+	n.bury(element)
+	element.acknowledge(nil, "", err)
+}
+
+// forward inserts the element on the node of its area, waiting for its
+// receipt until the deadline of the element.
+func (n *Node) forward(element *Element, contact domain.Contact, current string, hops []string) {
+	if !n.health.Allow(contact) {
+		n.settle(element, nil, "", fmt.Errorf("%w: %s", ErrCircuitOpen, contact.Name()))
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), element.deadline)
+	defer cancel()
+
+	owner, location, err := contact.Insert(ctx, element.item, element.policy, element.root, current, hops)
+	n.record(contact, err)
+	if err == nil && len(element.item.Idempotency) > 0 {
+		n.window.Add(idempotency(element.item), location)
+	}
+	n.settle(element, owner, location, err)
+}
+
+// retryable reports whether the insertion may succeed on another attempt.
+func retryable(err error) bool {
+	return !errors.Is(err, domain.ErrForbidden) && !errors.Is(err, domain.ErrInvalid) && !errors.Is(err, domain.ErrHops) &&
+		!errors.Is(err, domain.ErrUnauthorized)
+}
+
+// feed inserts the element, a panic failing the element only.
+func (n *Node) feed(element *Element) (contact domain.Contact, location string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return n.insert(context.Background(), element, element.current)
+}
+
+// fanout processes the tasks concurrently, with at most workers at a time.
+func fanout[T any](workers int, tasks []T, process func(T)) {
+	slots := make(chan struct{}, max(workers, 1))
+	wg := &sync.WaitGroup{}
+
+	for _, task := range tasks {
+		slots <- struct{}{}
+		wg.Add(1)
+
+		go func(task T) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			process(task)
+		}(task)
+	}
+
+	wg.Wait()
 }
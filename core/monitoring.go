@@ -94,3 +94,15 @@ func (n *Node) Check() []string {
 func (n *Node) Queue() int {
 	return n.queue.Length()
 }
+
+func (n *Node) Cache() domain.CacheStats {
+	return n.cache.Stats()
+}
+
+func (n *Node) Redirects() map[string]string {
+	result := make(map[string]string)
+	for key, contact := range n.redirects.List() {
+		result[fmt.Sprintf("%s:%s", key.Collection, key.Location)] = contact.Name()
+	}
+	return result
+}
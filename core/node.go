@@ -1,9 +1,14 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/indexus/go-indexus-core/domain"
@@ -18,8 +23,19 @@ type Node struct {
 	acknowledged *domain.BST[domain.Contact]
 	collections  *domain.Collections
 	owned        *domain.BST[map[domain.Key]any]
+	redirects    *domain.Redirects
 	cache        *domain.Cache
+	watchers     *domain.Watchers
 	queue        *domain.Queue[*Element]
+	journal      domain.Journal
+	seq          atomic.Uint64
+	meter        *domain.Meter
+	letters      *domain.DeadLetters
+	window       *domain.Window
+	hub          *domain.Hub
+	health       *domain.Health
+	items        atomic.Int64
+	balancing    atomic.Bool
 	storage      domain.Storage
 	ready        bool
 }
@@ -34,13 +50,20 @@ func NewNode(settings *Settings, newContact func(string, map[string]any, int) do
 		acknowledged: domain.NewBST[domain.Contact](),
 		collections:  domain.NewCollections(),
 		owned:        domain.NewBST[map[domain.Key]any](),
-		cache:        domain.NewCache(),
-		queue:        domain.NewQueue[*Element](),
+		redirects:    domain.NewRedirects(),
+		cache:        domain.NewCache(settings.cache, settings.cacheBytes, settings.expiration, settings.negative, settings.poll),
+		watchers:     domain.NewWatchers(2*settings.poll + settings.delay),
+		queue:        domain.NewQueue[*Element](settings.capacity),
+		meter:        domain.NewMeter(),
+		letters:      domain.NewDeadLetters(deadLetters),
+		window:       domain.NewWindow(settings.dedup, settings.dedupTTL),
+		hub:          domain.NewHub(),
+		health:       domain.NewHealth(settings.suspicion, settings.delay),
 		storage:      storage,
 	}
 
 	node.register([]domain.Contact{node})
-	node.acknowledge(bootstraps)
+	node.bootstrap()
 
 	if err := node.Restore(); err != nil {
 		fmt.Printf("issue when restoring the node from backup: %v", err)
@@ -79,11 +102,27 @@ func (n *Node) Host() string {
 	return fmt.Sprintf("%s@%s|%d", n.settings.name, n.settings.ip, n.settings.port)
 }
 
+func (n *Node) Load() domain.Load {
+	return domain.Load{
+		Items: int(n.items.Load()),
+		Rate:  n.meter.Rate(),
+		Queue: n.queue.Length(),
+	}
+}
+
+// Proof returns the proof of work of the id of the node, nil without identity.
+func (n *Node) Proof() *domain.Proof {
+	if n.settings.identity == nil {
+		return nil
+	}
+	return n.settings.identity.Proof()
+}
+
 func (n *Node) Delay() time.Duration {
 	return n.settings.delay
 }
 
-func (n *Node) Ping(origin domain.Contact) (domain.Contact, error) {
+func (n *Node) Ping(ctx context.Context, origin domain.Contact) (domain.Contact, error) {
 
 	if len(origin.Name()) > 0 {
 		n.acknowledge([]domain.Contact{origin})
@@ -92,7 +131,10 @@ func (n *Node) Ping(origin domain.Contact) (domain.Contact, error) {
 	return n, nil
 }
 
-func (n *Node) Neighbors(origin domain.Peer) ([]domain.Contact, error) {
+func (n *Node) Neighbors(ctx context.Context, origin domain.Peer) ([]domain.Contact, error) {
+	if err := n.member(origin); err != nil {
+		return nil, err
+	}
 
 	id, err := domain.DecodeName(origin.Name())
 	if err != nil {
@@ -111,7 +153,7 @@ func (n *Node) Neighbors(origin domain.Peer) ([]domain.Contact, error) {
 	return result, nil
 }
 
-func (n *Node) Random(origin domain.Peer) (domain.Contact, error) {
+func (n *Node) Random(ctx context.Context, origin domain.Peer) (domain.Contact, error) {
 
 	contacts := n.traverseRegistered(false)
 
@@ -122,45 +164,140 @@ func (n *Node) Random(origin domain.Peer) (domain.Contact, error) {
 	return contacts[rand.Intn(len(contacts))], nil
 }
 
-func (n *Node) Transfer(origin domain.Peer, key domain.Key, items []*domain.Item) error {
+func (n *Node) Transfer(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	if err := n.member(origin); err != nil {
+		return err
+	}
+	if err := domain.ValidateKey(key); err != nil {
+		return err
+	}
 
+	n.witness(key, witnesses)
+	n.redirect(key, redirects)
 	for _, item := range items {
-		n.New(item, key.Location, key.Location)
+		if err := n.receive(item, policy, key.Location, key.Location); err != nil && !errors.Is(err, domain.ErrInvalid) && !errors.Is(err, domain.ErrForbidden) {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (n *Node) Get(collection, location string) (domain.Contact, *domain.Set, error) {
+func (n *Node) Adopt(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	if err := n.member(origin); err != nil {
+		return err
+	}
+	if err := domain.ValidateKey(key); err != nil {
+		return err
+	}
+
+	n.redirects.Set(key, n)
+	n.create(key.Collection, key.Location, policy)
+	n.witness(key, witnesses)
+	n.redirect(key, redirects)
 
-	name := collection
-	if location != domain.Root() {
-		name = location + collection[len(location):]
+	for _, item := range items {
+		if err := n.receive(item, policy, key.Location, item.Location); err != nil && !errors.Is(err, domain.ErrInvalid) && !errors.Is(err, domain.ErrForbidden) {
+			return err
+		}
 	}
 
-	id, err := domain.DecodeName(name)
-	if err != nil {
-		return nil, nil, err
+	return nil
+}
+
+// witnesses returns the idempotency keys of the items inserted in the area.
+func (n *Node) witnesses(key domain.Key) []domain.Witness {
+	witnesses := make([]domain.Witness, 0)
+	for _, witness := range n.window.List() {
+		if inside(witness, key) {
+			witnesses = append(witnesses, witness)
+		}
 	}
+	return witnesses
+}
 
-	nearest := n.registered.Nearest(0, id)
+// witness remembers the idempotency keys handed over with the area, those of
+// other areas being ignored.
+func (n *Node) witness(key domain.Key, witnesses []domain.Witness) {
+	accepted := make([]domain.Witness, 0, len(witnesses))
+	for _, witness := range witnesses {
+		if inside(witness, key) {
+			accepted = append(accepted, witness)
+		}
+	}
+	n.window.Put(accepted...)
+}
 
-	if collection, exist := n.collections.Get(collection); exist {
-		set, ok := collection.Get(location)
-		if ok {
-			return nearest, set, nil
+// redirect takes the redirects handed over with the area, those of other
+// areas being ignored. The peers already registered are reached through
+// their contact.
+func (n *Node) redirect(key domain.Key, redirects []domain.Redirect) {
+	for _, redirect := range redirects {
+		if redirect.Key.Collection != key.Collection || !domain.Within(redirect.Key.Location, key.Location) {
+			continue
 		}
+		contact := redirect.Contact
+		if contact.Name() == n.Name() {
+			contact = n
+		} else if registered, exist := n.registered.Get(0, contact.ID()); exist {
+			contact = registered
+		}
+		n.redirects.Set(redirect.Key, contact)
+	}
+}
+
+// idempotency returns the key of the item in the window.
+func idempotency(item *domain.Item) string {
+	return item.Collection + "|" + item.Idempotency
+}
+
+func inside(witness domain.Witness, key domain.Key) bool {
+	return strings.HasPrefix(witness.Key, key.Collection+"|") && domain.Within(witness.Area, key.Location)
+}
+
+// receive queues an item handed over by a peer. The peer no longer holds it,
+// so that it is queued whatever the bound of the queue, like the items
+// replayed after a restart.
+func (n *Node) receive(item *domain.Item, policy domain.Policy, root, current string) error {
+	n.meter.Mark()
+	if err := validate(item, root, current); err != nil {
+		return err
+	}
+	if err := n.authorize(item, policy); err != nil {
+		return err
+	}
+
+	element := NewElement(item, policy, root, current, nil)
+	if err := n.journalize(element); err != nil {
+		return err
+	}
+	n.queue.Put(element)
+	return nil
+}
+
+// Get returns the set of the area and the node it is routed to. It fails with
+// domain.ErrNotModified when the set is still at the version, 0 for none.
+func (n *Node) Get(ctx context.Context, collection, location string, version uint64) (domain.Contact, *domain.Set, error) {
+	n.meter.Mark()
+
+	if err := domain.ValidateKey(domain.Key{Collection: collection, Location: location}); err != nil {
+		return nil, nil, err
+	}
+
+	nearest, err := n.find(collection, location)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	if collection, exist := n.collections.Get(collection); exist {
 		set, ok := collection.Get(location)
 		if ok {
-			return nearest, set, nil
+			return unchanged(nearest, set, version)
 		}
 	}
 
 	if set, exist := n.cache.Get(collection, location); exist {
-		return nearest, set, nil
+		return unchanged(nearest, set, version)
 	}
 
 	n.cache.Set(collection, location, nil)
@@ -168,16 +305,137 @@ func (n *Node) Get(collection, location string) (domain.Contact, *domain.Set, er
 	return nearest, nil, nil
 }
 
-func (n *Node) New(item *domain.Item, root, current string) error {
-	n.queue.Add(NewElement(item, root, current))
+// Changes returns the delta of the set of the area since the version, the
+// whole set when the journal of the set does not reach back to the version.
+func (n *Node) Changes(ctx context.Context, collection, location string, version uint64) (domain.Contact, *domain.Delta, error) {
+	contact, set, err := n.Get(ctx, collection, location, version)
+	if err != nil || set == nil {
+		return contact, nil, err
+	}
+	return contact, set.Since(version), nil
+}
+
+func unchanged(contact domain.Contact, set *domain.Set, version uint64) (domain.Contact, *domain.Set, error) {
+	if set != nil && version != 0 && set.Version() == version {
+		return contact, nil, domain.ErrNotModified
+	}
+	return contact, set, nil
+}
+
+func (n *Node) New(ctx context.Context, item *domain.Item, policy domain.Policy, root, current string, hops []string) error {
+	n.meter.Mark()
+	if err := validate(item, root, current); err != nil {
+		return err
+	}
+	if err := n.authorize(item, policy); err != nil {
+		return err
+	}
+	return n.enqueue(NewElement(item, policy, root, current, hops))
+}
+
+// Insert queues the item like New, and waits until the node of its area added
+// it to return that node and the area. It fails with domain.ErrPending when
+// the wait ends first, the item staying queued.
+func (n *Node) Insert(ctx context.Context, item *domain.Item, policy domain.Policy, root string, current string, hops []string) (domain.Contact, string, error) {
+	n.meter.Mark()
+	if err := validate(item, root, current); err != nil {
+		return nil, "", err
+	}
+	if err := n.authorize(item, policy); err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.settings.ackWait)
+	defer cancel()
+
+	element := NewElement(item, policy, root, current, hops)
+	element.ack = make(chan receipt, 1)
+	element.deadline, _ = ctx.Deadline()
+	if err := n.enqueue(element); err != nil {
+		return nil, "", err
+	}
+
+	select {
+	case r := <-element.ack:
+		return r.contact, r.location, r.err
+	case <-ctx.Done():
+		return nil, "", fmt.Errorf("%w: %v", domain.ErrPending, ctx.Err())
+	}
+}
+
+func validate(item *domain.Item, root, current string) error {
+	if err := item.Validate(); err != nil {
+		return err
+	}
+	if err := domain.ValidateLocation("root", root); err != nil {
+		return err
+	}
+	return domain.ValidateLocation("current", current)
+}
+
+// authorize checks the item against the policy of the collection, the one
+// given with the item when the collection is not held by the node.
+func (n *Node) authorize(item *domain.Item, policy domain.Policy) error {
+	if collection, exist := n.collections.Get(item.Collection); exist {
+		policy = collection.Policy()
+	}
+	return policy.Authorize(item)
+}
+
+// deadline bounds a call to a peer by the configured timeout.
+func (n *Node) deadline(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, n.settings.timeout)
+}
+
+// bootstrap acknowledges the bootstrap peers, whose names are unknown until
+// they answer Ping.
+func (n *Node) bootstrap() {
+	for _, candidate := range n.bootstraps {
+		_, exist := n.registered.Get(0, candidate.ID())
+		if !exist {
+			n.acknowledged.Insert(0, candidate.ID(), candidate)
+		}
+	}
+}
+
+// Known reports whether the peer is registered by the node, its proof of work
+// having been checked against the difficulty of the node when it joined.
+func (n *Node) Known(peer domain.Peer) bool {
+	_, exist := n.registered.Get(0, peer.ID())
+	return exist && peer.Name() != n.Name()
+}
+
+// member checks that the origin is a peer registered by the node, which the
+// areas are handed over by and the routing table is shared with.
+func (n *Node) member(origin domain.Peer) error {
+	if !n.Known(origin) {
+		return fmt.Errorf("%w: %s is not a known peer", domain.ErrUnauthorized, origin.Name())
+	}
 	return nil
 }
 
+// admit checks the proof of work of the id of the contact when the node
+// requires one.
+func (n *Node) admit(contact domain.Contact) bool {
+	if n.settings.difficulty <= 0 {
+		return true
+	}
+
+	prover, ok := contact.(domain.Prover)
+	if !ok || prover.Proof() == nil {
+		return false
+	}
+	return prover.Proof().Verify(contact.Name(), n.settings.difficulty) == nil
+}
+
 func (n *Node) acknowledge(candidates []domain.Contact) {
 	if len(candidates) == 0 {
 		return
 	}
 	for _, candidate := range candidates {
+		if !n.admit(candidate) {
+			continue
+		}
 		_, exist := n.registered.Get(0, candidate.ID())
 		if !exist {
 			n.acknowledged.Insert(0, candidate.ID(), candidate)
@@ -200,6 +458,9 @@ func (n *Node) register(contacts []domain.Contact) {
 	}
 	for _, contact := range contacts {
 		_ = n.acknowledged.Remove(0, contact.ID())
+		if !n.admit(contact) {
+			continue
+		}
 		_, exist := n.registered.Get(0, contact.ID())
 		if !exist {
 			n.registered.Insert(0, contact.ID(), contact)
@@ -217,6 +478,10 @@ func (n *Node) reject(contacts []domain.Contact) {
 	for _, contact := range contacts {
 		n.registered.Remove(0, contact.ID())
 	}
+	for _, contact := range contacts {
+		n.health.Forget(contact)
+		n.reclaim(contact)
+	}
 }
 
 func (n *Node) subscribe(contacts []domain.Contact) {
@@ -230,12 +495,11 @@ func (n *Node) subscribe(contacts []domain.Contact) {
 
 func (n *Node) find(collection, location string) (domain.Contact, error) {
 
-	name := collection
-	if location != domain.Root() {
-		name = location + collection[len(location):]
+	if contact, exist := n.redirects.Resolve(collection, location); exist {
+		return contact, nil
 	}
 
-	id, err := domain.DecodeName(name)
+	id, err := domain.DecodeLocation(collection, location)
 	if err != nil {
 		return nil, err
 	}
@@ -282,9 +546,9 @@ func (n *Node) traverseRouting(self bool) []domain.Contact {
 	return contacts
 }
 
-func (n *Node) clean() error {
+func (n *Node) clean(ctx context.Context) error {
 
-	neighbors, err := n.Neighbors(n)
+	neighbors, err := n.Neighbors(ctx, n)
 	if err != nil {
 		return err
 	}
@@ -295,41 +559,90 @@ func (n *Node) clean() error {
 	return nil
 }
 
-func (n *Node) insert(item *domain.Item, root, current string) error {
+// insert adds the element to the area of the node or forwards it to the node
+// of its area. It returns the node and the area the item was added to, none
+// when the insertion goes on elsewhere.
+func (n *Node) insert(ctx context.Context, element *Element, current string) (domain.Contact, string, error) {
+	item, policy, root, hops := element.item, element.policy, element.root, element.hops
+
+	// Repeated deliveries of a write are acknowledged without being counted,
+	// by the owner of its area or any node it went through, as the area may
+	// have moved since
+	key := idempotency(item)
+	if len(item.Idempotency) > 0 {
+		if area, seen := n.window.Seen(key); seen {
+			owner, err := n.find(item.Collection, area)
+			if err != nil {
+				owner = n
+			}
+			return owner, area, nil
+		}
+	}
 
 	contact, err := n.find(item.Collection, current)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	if n.Name() != contact.Name() {
-		contact.New(item, root, current)
-		return nil
+		if len(hops) >= n.settings.hops {
+			return nil, "", domain.ErrHops
+		}
+		hops = append(slices.Clip(hops), n.Name())
+		if element.waited() {
+			go n.forward(element, contact, current, hops)
+			return nil, "", errForwarding
+		}
+		// The element is queued again if the call fails
+		err := n.once(ctx, contact, func(ctx context.Context) error {
+			return contact.New(ctx, item, policy, root, current, hops)
+		})
+		if err == nil && len(item.Idempotency) > 0 {
+			n.window.Add(key, current)
+		}
+		return nil, "", err
+	}
+
+	// The policy may have been unknown when the item was queued
+	if err := n.authorize(item, policy); err != nil {
+		return nil, "", err
 	}
 
 	if current == root {
-		n.create(item.Collection, root)
+		n.create(item.Collection, root, policy)
 	}
 
-	if n.add(item) {
-		return nil
+	// A client waiting for the item is answered once it is written
+	if area, added := n.add(item); added {
+		if len(item.Idempotency) > 0 {
+			n.window.Add(key, area)
+		}
+		if err := n.persist(item, element.waited()); err != nil {
+			return nil, "", fmt.Errorf("%w: added but not persisted: %v", domain.ErrPending, err)
+		}
+		return n, area, nil
 	}
 
 	current = domain.Parent(current)
 
+	// The item bounces until the area it belongs to is created
 	if len(current) == 0 {
-		n.New(item, root, item.Location)
-		return nil
+		if len(hops) >= n.settings.hops {
+			return nil, "", domain.ErrHops
+		}
+		bounced := NewElement(item, policy, root, item.Location, append(slices.Clip(hops), n.Name()))
+		bounced.ack, bounced.deadline = element.ack, element.deadline
+		return nil, "", n.enqueue(bounced)
 	}
 
-	return n.insert(item, root, current)
+	return n.insert(ctx, element, current)
 }
 
-func (n *Node) create(col, root string) {
+func (n *Node) create(col, root string, policy domain.Policy) {
 
 	collection, exist := n.collections.Get(col)
 	if !exist {
-		collection = domain.NewCollection(col, root)
+		collection = domain.NewCollection(col, root, policy.WithDefaults(n.settings.policy()))
 	}
 
 	_, exist = collection.Get(root)
@@ -342,23 +655,39 @@ func (n *Node) create(col, root string) {
 	n.collections.Set(collection)
 }
 
-func (n *Node) add(item *domain.Item) bool {
+// add adds the item to the area of the node it belongs to, returned with
+// false when the node does not own it.
+func (n *Node) add(item *domain.Item) (string, bool) {
 
 	collection, exist := n.collections.Get(item.Collection)
-	if !exist || !collection.Allowing(item.Location) {
-		return false
+	if !exist {
+		return "", false
 	}
-
-	areas := collection.Add(item.Location, item.Id, n.settings.setLength, n.settings.delegation)
-	if n.ready {
-		n.storage.Append(item.Content())
+	area, allowed := collection.Area(item.Location)
+	if !allowed {
+		return "", false
 	}
 
+	areas := collection.Add(item)
+
 	if len(areas) > 0 {
 		n.own(collection, areas)
 	}
 
-	return true
+	n.publish(domain.Added, area, "", item)
+	return area, true
+}
+
+// persist appends the item to the log, waiting for the write when durable.
+func (n *Node) persist(item *domain.Item, durable bool) error {
+	if !n.ready {
+		return nil
+	}
+	if durable {
+		return n.storage.Commit(record(item))
+	}
+	n.storage.Append(record(item))
+	return nil
 }
 
 func (n *Node) own(collection *domain.Collection, owned domain.Ownership) {
@@ -378,30 +707,47 @@ func (n *Node) own(collection *domain.Collection, owned domain.Ownership) {
 	}
 }
 
-func (n *Node) control() map[domain.Contact]map[domain.Key][]*domain.Item {
+func (n *Node) control() (map[domain.Contact]map[domain.Key][]*domain.Item, map[string]domain.Policy) {
 
 	transferable := make(map[domain.Contact]map[domain.Key][]*domain.Item)
+	policies := make(map[string]domain.Policy)
 	for _, candidate := range n.traverseRouting(false) {
 
+		adopted := make([]domain.Key, 0)
+
 		n.owned.Range(0, n.ID(), candidate.ID(), make([]byte, domain.IdLength()), func(idx int, id []byte, sets map[domain.Key]any) {
 			for key := range sets {
 
+				if contact, exist := n.redirects.Get(key); exist && contact.Name() == n.Name() {
+					adopted = append(adopted, key)
+					continue
+				}
+
 				_, exist := transferable[candidate]
 				if !exist {
 					transferable[candidate] = make(map[domain.Key][]*domain.Item, 0)
 				}
 
 				collection, _ := n.collections.Get(key.Collection)
+				policies[key.Collection] = collection.Policy()
 
 				items, empty := collection.Delegate(key.Location)
 				if empty {
 					n.collections.Delete(key.Collection)
 				}
+				n.publish(domain.Removed, key.Location, candidate.Name(), items...)
 
 				transferable[candidate][key] = items
 			}
 		})
 		n.owned.Truncate(0, n.ID(), candidate.ID())
+
+		// Adopted areas stay on the node whatever the distance to the candidate
+		for _, key := range adopted {
+			if collection, exist := n.collections.Get(key.Collection); exist {
+				n.own(collection, domain.Ownership{key.Location: {}})
+			}
+		}
 	}
-	return transferable
+	return transferable, policies
 }
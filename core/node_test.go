@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// memory is a storage keeping nothing, the node starting empty.
+type memory struct{}
+
+func (memory) Exist() bool              { return false }
+func (memory) Reset() error             { return nil }
+func (memory) Save([]string) error      { return nil }
+func (memory) Load() ([]string, error)  { return nil, nil }
+func (memory) Append(string)            {}
+func (memory) Commit(string) error      { return nil }
+func (memory) Stream(int) <-chan string { return nil }
+
+func name(t *testing.T) string {
+	t.Helper()
+	identity, err := domain.NewIdentity(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity.Name()
+}
+
+func newTestNode(t *testing.T) *Node {
+	t.Helper()
+	settings, err := NewSettings(name(t), 0, time.Second, time.Minute, 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newContact := func(name string, _ map[string]any, _ int) domain.Contact { return &stub{name: name} }
+	n, err := NewNode(settings, newContact, nil, memory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPeerCallsAreRefusedToUnknownOrigins(t *testing.T) {
+	n := newTestNode(t)
+	stranger := &stub{name: name(t)}
+	key := domain.Key{Collection: name(t), Location: domain.Root()}
+	ctx := context.Background()
+
+	if err := n.Adopt(ctx, stranger, key, domain.Policy{}, nil, nil, nil); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("adoption from an unknown origin answered %v", err)
+	}
+	if err := n.Transfer(ctx, stranger, key, domain.Policy{}, nil, nil, nil); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("transfer from an unknown origin answered %v", err)
+	}
+	if _, err := n.Neighbors(ctx, stranger); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("neighbors of an unknown origin answered %v", err)
+	}
+	if _, exist := n.collections.Get(key.Collection); exist {
+		t.Error("collection created by an unknown origin")
+	}
+
+	n.register([]domain.Contact{stranger})
+	if _, err := n.Neighbors(ctx, stranger); err != nil {
+		t.Errorf("neighbors of a registered peer answered %v", err)
+	}
+	if err := n.Adopt(ctx, stranger, key, domain.Policy{}, nil, nil, nil); err != nil {
+		t.Errorf("adoption from a registered peer answered %v", err)
+	}
+}
+
+// owner is a peer owning the areas redirected to it, counting the items.
+type owner struct {
+	stub
+	items int
+}
+
+func (o *owner) New(ctx context.Context, item *domain.Item, policy domain.Policy, root, current string, hops []string) error {
+	o.items++
+	return nil
+}
+
+func TestRepeatedWritesAreAcknowledgedByTheEntryNode(t *testing.T) {
+	n := newTestNode(t)
+	peer := &owner{stub: stub{name: name(t)}}
+	collection := name(t)
+	n.redirects.Set(domain.Key{Collection: collection, Location: domain.Root()}, peer)
+
+	item := &domain.Item{Collection: collection, Location: "AB", Id: "i", Idempotency: "k"}
+	for i := 0; i < 2; i++ {
+		element := NewElement(item, domain.Policy{}, domain.Root(), item.Location, nil)
+		if _, _, err := n.insert(context.Background(), element, element.current); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if peer.items != 1 {
+		t.Errorf("write forwarded %d times, want once", peer.items)
+	}
+
+	// The owner is answered from the area the item was handed to
+	element := NewElement(item, domain.Policy{}, domain.Root(), item.Location, nil)
+	contact, area, err := n.insert(context.Background(), element, element.current)
+	if err != nil || contact.Name() != peer.Name() || area != item.Location {
+		t.Errorf("repeated write answered %v, %q, %v", contact, area, err)
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// deadLetters is the number of items given up kept by the node.
+const deadLetters = 10_000
+
+// Recover queues the elements journaled but not processed before the node
+// stopped, and journals the next ones. It must be called before Feed.
+func (n *Node) Recover(journal domain.Journal) error {
+	entries, err := journal.Replay()
+	if err != nil {
+		return err
+	}
+	n.journal = journal
+
+	// The replayed elements stay journaled under their position
+	n.seq.Store(uint64(len(entries)))
+	for i, data := range entries {
+		element := &Element{}
+		if err := json.Unmarshal(data, element); err != nil {
+			log.Println("Error decoding journaled element: ", err)
+			n.forget(uint64(i + 1))
+			continue
+		}
+		element.seq = uint64(i + 1)
+		if len(element.reason) > 0 {
+			n.bury(element)
+			continue
+		}
+		n.queue.Put(element)
+	}
+	return nil
+}
+
+// enqueue journals the element and queues it, failing with domain.ErrFull
+// when the queue stays full for the configured wait.
+func (n *Node) enqueue(element *Element) error {
+	if err := n.journalize(element); err != nil {
+		return err
+	}
+	if err := n.queue.Add(element, n.settings.wait); err != nil {
+		n.done(element)
+		return err
+	}
+	return nil
+}
+
+func (n *Node) journalize(element *Element) error {
+	if n.journal == nil {
+		return nil
+	}
+	element.seq = n.seq.Add(1)
+	data, err := json.Marshal(element)
+	if err != nil {
+		return err
+	}
+	return n.journal.Append(element.seq, data)
+}
+
+// done removes the element from the journal once processed.
+func (n *Node) done(element *Element) {
+	n.forget(element.seq)
+}
+
+func (n *Node) forget(seq uint64) {
+	if n.journal == nil || seq == 0 {
+		return
+	}
+	if err := n.journal.Done(seq); err != nil {
+		log.Println("Error journaling element: ", err)
+	}
+}
+
+// bury keeps the element given up as a dead letter, which stays in the
+// journal until it is replayed or dropped for a newer one.
+func (n *Node) bury(element *Element) {
+	processed := element.seq
+	if err := n.journalize(element); err != nil {
+		log.Println("Error journaling dead letter: ", err)
+	}
+	n.forget(processed)
+
+	dropped := n.letters.Add(domain.Letter{
+		Item:     element.item,
+		Policy:   element.policy,
+		Root:     element.root,
+		Current:  element.current,
+		Hops:     element.hops,
+		Attempts: element.attempts,
+		Reason:   element.reason,
+		Time:     element.failed,
+		Journal:  element.seq,
+	})
+	if dropped != nil {
+		n.forget(dropped.Journal)
+	}
+}
+
+// DeadLetters lists the items the node gave up inserting.
+func (n *Node) DeadLetters() []domain.Letter {
+	return n.letters.List()
+}
+
+// Replay queues again the letters of the ids, all of them when none is given,
+// and returns the number of letters replayed.
+func (n *Node) Replay(ids ...uint64) (int, error) {
+	wanted := make(map[uint64]bool)
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	count := 0
+	for _, letter := range n.letters.List() {
+		if len(ids) > 0 && !wanted[letter.Id] {
+			continue
+		}
+		if err := n.enqueue(NewElement(letter.Item, letter.Policy, letter.Root, letter.Current, nil)); err != nil {
+			return count, err
+		}
+		if n.letters.Remove(letter.Id) {
+			n.forget(letter.Journal)
+		}
+		count++
+	}
+	return count, nil
+}
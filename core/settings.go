@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"net"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 type Settings struct {
 	id         []byte
 	name       string
+	identity   *domain.Identity
 	ip         string
 	ips        map[string]any
 	port       int
@@ -17,6 +19,25 @@ type Settings struct {
 	expiration time.Duration
 	delegation int
 	setLength  int
+	rebalance  float64
+	timeout    time.Duration
+	workers    int
+	attempts   int
+	backoff    time.Duration
+	suspicion  int
+	difficulty int
+	capacity   int
+	wait       time.Duration
+	feeders    int
+	hops       int
+	ackWait    time.Duration
+	dedup      int
+	dedupTTL   time.Duration
+	cache      int
+	cacheBytes int
+	negative   time.Duration
+	push       time.Duration
+	poll       time.Duration
 }
 
 func NewSettings(name string, port int, delay, expiration time.Duration, delegation int, setLength int) (*Settings, error) {
@@ -36,9 +57,124 @@ func NewSettings(name string, port int, delay, expiration time.Duration, delegat
 		expiration: expiration,
 		delegation: delegation,
 		setLength:  setLength,
+		timeout:    200 * time.Millisecond,
+		workers:    16,
+		attempts:   3,
+		backoff:    50 * time.Millisecond,
+		suspicion:  3,
+		capacity:   100_000,
+		feeders:    4,
+		hops:       32,
+		ackWait:    30 * time.Second,
+		dedup:      100_000,
+		dedupTTL:   time.Hour,
+		cache:      10_000,
+		cacheBytes: 64 << 20,
+		negative:   30 * time.Second,
+		push:       100 * time.Millisecond,
+		poll:       time.Minute,
 	}, nil
 }
 
+// SetIdentity sets the keypair of the node, the name must be derived from its
+// public key.
+func (s *Settings) SetIdentity(identity *domain.Identity) error {
+	if identity.Name() != s.name {
+		return fmt.Errorf("name %s does not match the identity %s", s.name, identity.Name())
+	}
+	s.identity = identity
+	return nil
+}
+
+// SetRebalance enables the handover of hot areas to peers whose load is
+// below the load of the node divided by factor, 0 disables it.
+func (s *Settings) SetRebalance(factor float64) {
+	s.rebalance = factor
+}
+
+// SetTimeout sets the deadline of every call to a peer.
+func (s *Settings) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+// SetQueue bounds the ingestion queue to capacity elements, 0 for no bound,
+// an item waiting up to wait for room when it is full.
+func (s *Settings) SetQueue(capacity int, wait time.Duration) {
+	s.capacity = capacity
+	s.wait = wait
+}
+
+// SetFeeders sets the number of collections whose items are inserted
+// concurrently.
+func (s *Settings) SetFeeders(feeders int) {
+	s.feeders = max(feeders, 1)
+}
+
+// SetHops sets the number of nodes an item may go through before being
+// given up.
+func (s *Settings) SetHops(hops int) {
+	s.hops = max(hops, 1)
+}
+
+// SetAcknowledgment sets the longest wait for the receipt of an insertion.
+func (s *Settings) SetAcknowledgment(timeout time.Duration) {
+	s.ackWait = timeout
+}
+
+// SetDedup sets the number of idempotency keys remembered by the node and
+// how long, 0 keys disabling the deduplication.
+func (s *Settings) SetDedup(keys int, window time.Duration) {
+	s.dedup = keys
+	s.dedupTTL = window
+}
+
+// SetCache bounds the cache of the sets of other nodes to entries and bytes,
+// 0 for no bound, the areas found empty being cached for negative.
+func (s *Settings) SetCache(entries, bytes int, negative time.Duration) {
+	s.cache = entries
+	s.cacheBytes = bytes
+	s.negative = negative
+}
+
+// SetPush sets the delay gathering the changes of the sets of the node before
+// they are pushed to the peers caching them, and the interval of the polling
+// of the sets pushed by their owners.
+func (s *Settings) SetPush(delay, poll time.Duration) {
+	s.push = delay
+	s.poll = poll
+}
+
+// SetWorkers sets the number of peers called concurrently by the recurring jobs.
+func (s *Settings) SetWorkers(workers int) {
+	s.workers = max(workers, 1)
+}
+
+// SetRetry sets the number of attempts of a call to a peer and the backoff
+// before the first retry, doubled on every retry.
+func (s *Settings) SetRetry(attempts int, backoff time.Duration) {
+	s.attempts = max(attempts, 1)
+	s.backoff = backoff
+}
+
+// SetSuspicion sets the number of failed calls after which a suspected peer
+// is rejected.
+func (s *Settings) SetSuspicion(failures int) {
+	s.suspicion = max(failures, 1)
+}
+
+// SetDifficulty sets the proof of work required from the ids of the peers,
+// as leading zero bits, 0 disables it.
+func (s *Settings) SetDifficulty(difficulty int) {
+	s.difficulty = difficulty
+}
+
+func (s *Settings) policy() domain.Policy {
+	return domain.Policy{
+		Delegation: s.delegation,
+		SetLength:  s.setLength,
+	}
+}
+
 // getPublicIPs retrieves all public IPv4 and IPv6 addresses and returns them in a map[string]any.
 func getPublicIPs() map[string]any {
 	ips := make(map[string]any)
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/indexus/go-indexus-core/domain"
 )
@@ -24,7 +27,13 @@ func (n *Node) Snapshot() []string {
 	})
 
 	for _, collection := range n.collections.List() {
-		snapshot = append(snapshot, fmt.Sprintf("collection|%s", collection.Name()))
+		policy := collection.Policy()
+		writers := make([]string, 0, len(policy.Writers))
+		for _, writer := range policy.Writers {
+			writers = append(writers, base64.StdEncoding.EncodeToString(writer))
+		}
+		snapshot = append(snapshot, fmt.Sprintf("collection|%s|%d|%d|%s|%s|%s", collection.Name(), policy.Delegation, policy.SetLength,
+			policy.Access, base64.StdEncoding.EncodeToString(policy.Owner), strings.Join(writers, ",")))
 
 		collection.Browse(
 			func(ownership string) {
@@ -35,6 +44,20 @@ func (n *Node) Snapshot() []string {
 			},
 		)
 	}
+
+	for key, c := range n.redirects.List() {
+		arr := make([]string, 0)
+		for ip := range c.IPs() {
+			arr = append(arr, ip)
+		}
+		snapshot = append(snapshot, fmt.Sprintf("redirect|%s|%s|%s|%s|%d", key.Collection, key.Location, c.Name(), strings.Join(arr, ","), c.Port()))
+	}
+
+	// The idempotency keys may hold any character
+	for _, witness := range n.window.List() {
+		snapshot = append(snapshot, fmt.Sprintf("idempotency|%s|%s|%d", base64.StdEncoding.EncodeToString([]byte(witness.Key)),
+			base64.StdEncoding.EncodeToString([]byte(witness.Area)), witness.Time.UnixNano()))
+	}
 	return snapshot
 }
 
@@ -49,6 +72,8 @@ func (n *Node) Restore() error {
 	}
 
 	var collection, ownership, delegation string
+	var policy domain.Policy
+	witnesses := make([]domain.Witness, 0)
 	for _, command := range commands {
 		arr := strings.Split(command, "|")
 		if len(arr) == 0 {
@@ -57,7 +82,7 @@ func (n *Node) Restore() error {
 
 		switch arr[0] {
 		case "contact":
-			if len(arr) != 4 {
+			if len(arr) != 4 || domain.ValidateName("name", arr[1]) != nil {
 				continue
 			}
 			name := arr[1]
@@ -72,27 +97,89 @@ func (n *Node) Restore() error {
 			}
 			n.acknowledged.Insert(0, make([]byte, domain.IdLength()), n.newContact(name, mIps, port))
 		case "collection":
-			collection = arr[1]
+			if len(arr) < 2 {
+				return errors.New("backup file is corrupted and cannot be restored")
+			}
+			collection, policy = arr[1], domain.Policy{}
+			if len(arr) >= 4 {
+				policy.Delegation, _ = strconv.Atoi(arr[2])
+				policy.SetLength, _ = strconv.Atoi(arr[3])
+			}
+			if len(arr) == 7 {
+				policy.Access = arr[4]
+				policy.Owner = decodeKey(arr[5])
+				for _, writer := range strings.Split(arr[6], ",") {
+					if key := decodeKey(writer); key != nil {
+						policy.Writers = append(policy.Writers, key)
+					}
+				}
+			}
 		case "ownership":
+			if len(arr) < 2 || len(collection) == 0 || domain.ValidateLocation("ownership", arr[1]) != nil {
+				return errors.New("backup file is corrupted and cannot be restored")
+			}
 			ownership = arr[1]
-			n.create(collection, ownership)
+			n.create(collection, ownership, policy)
 		case "delegation":
+			c, exist := n.collections.Get(collection)
+			if len(arr) < 2 || !exist {
+				return errors.New("backup file is corrupted and cannot be restored")
+			}
 			delegation = arr[1]
-			c, _ := n.collections.Get(collection)
 			c.Delegate(delegation)
+		case "redirect":
+			if len(arr) != 6 || domain.ValidateName("name", arr[3]) != nil {
+				continue
+			}
+			key := domain.Key{Collection: arr[1], Location: arr[2]}
+			if domain.ValidateKey(key) != nil {
+				continue
+			}
+			port, err := strconv.Atoi(arr[5])
+			if err != nil {
+				continue
+			}
+			mIps := make(map[string]any)
+			for _, ip := range strings.Split(arr[4], ",") {
+				mIps[ip] = nil
+			}
+			n.redirects.Set(key, n.newContact(arr[3], mIps, port))
+		case "idempotency":
+			if len(arr) != 4 {
+				continue
+			}
+			key, errKey := base64.StdEncoding.DecodeString(arr[1])
+			area, errArea := base64.StdEncoding.DecodeString(arr[2])
+			nanos, errTime := strconv.ParseInt(arr[3], 10, 64)
+			if errKey != nil || errArea != nil || errTime != nil {
+				continue
+			}
+			witnesses = append(witnesses, domain.Witness{Key: string(key), Area: string(area), Time: time.Unix(0, nanos)})
 		default:
 			return errors.New("backup file is corrupted and cannot be restored")
 		}
 	}
+	n.window.Put(witnesses...)
 
 	stream := n.storage.Stream(0)
 	for log := range stream {
 		arr := strings.Split(log, "|")
+		// The last line may be truncated by a crash
+		if len(arr) < 3 {
+			continue
+		}
 		item := &domain.Item{
 			Collection: arr[0],
 			Location:   arr[1],
 			Id:         arr[2],
 		}
+		if len(arr) == 5 {
+			item.Writer = decodeKey(arr[3])
+			item.Signature, _ = base64.StdEncoding.DecodeString(arr[4])
+		}
+		if item.Validate() != nil {
+			continue
+		}
 		if _, exist := n.collections.Get(item.Collection); exist {
 			n.add(item)
 		}
@@ -100,3 +187,19 @@ func (n *Node) Restore() error {
 
 	return nil
 }
+
+// record is the line of the log of an item, with its signature if any.
+func record(item *domain.Item) string {
+	if len(item.Signature) == 0 {
+		return item.Content()
+	}
+	return fmt.Sprintf("%s|%s|%s", item.Content(), base64.StdEncoding.EncodeToString(item.Writer), base64.StdEncoding.EncodeToString(item.Signature))
+}
+
+func decodeKey(value string) ed25519.PublicKey {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil
+	}
+	return key
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// events is the number of events a subscription may fall behind before it is
+// ended.
+const events = 256
+
+// refollow bounds the backoff before following again a relayed subscription
+// which ended.
+const refollow = time.Minute
+
+// relayed tells that the subscription to the area on a node ended, and
+// whether it delivered events.
+type relayed struct {
+	key       string
+	area      string
+	delivered bool
+}
+
+// Subscribe streams the events of the items of the collection at the location
+// or below until the context ends, the channel being closed when the
+// subscription ends. The events of the node owning the location and of the
+// nodes owning the areas delegated below it are relayed from them. The nodes
+// a subscription was relayed from, which send their own events, are given in
+// hops.
+func (n *Node) Subscribe(ctx context.Context, collection, location string, hops []string) (<-chan domain.Event, error) {
+	if err := domain.ValidateKey(domain.Key{Collection: collection, Location: location}); err != nil {
+		return nil, err
+	}
+	if len(hops) >= n.settings.hops {
+		return nil, domain.ErrHops
+	}
+
+	local, cancel := n.hub.Subscribe(collection, location, events)
+	out := make(chan domain.Event, events)
+	go n.relay(ctx, collection, location, append(slices.Clip(hops), n.Name()), local, cancel, out)
+	return out, nil
+}
+
+// relay merges the events of the node with the events of the nodes owning
+// the areas it delegated, following the areas handed over while subscribed.
+// A relayed subscription which ends is followed again with a backoff,
+// doubled while it ends without delivering events.
+func (n *Node) relay(ctx context.Context, collection, location string, hops []string, local <-chan domain.Event, cancel func(), out chan<- domain.Event) {
+	ctx, stop := context.WithCancel(ctx)
+	defer close(out)
+	defer stop()
+	defer cancel()
+
+	remote := make(chan domain.Event, events)
+	ended := make(chan relayed)
+	retry := make(chan string)
+	following := make(map[string]bool)
+	failures := make(map[string]int)
+
+	follow := func(area string) {
+		// The subscription covers the deepest of both locations
+		if strings.HasPrefix(location, area) || area == domain.Root() {
+			area = location
+		}
+		contact, err := n.find(collection, area)
+		if err != nil || contact.Name() == n.Name() || slices.Contains(hops, contact.Name()) {
+			return
+		}
+		key := contact.Name() + "|" + area
+		if following[key] {
+			return
+		}
+		following[key] = true
+
+		go func() {
+			end := relayed{key: key, area: area}
+			defer func() {
+				select {
+				case ended <- end:
+				case <-ctx.Done():
+				}
+			}()
+			events, err := contact.Subscribe(ctx, collection, area, hops)
+			if err != nil {
+				log.Printf("Error subscribing to %s: %v", contact.Name(), err)
+				return
+			}
+			for event := range events {
+				end.delivered = true
+				select {
+				case remote <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	follow(location)
+	if c, exist := n.collections.Get(collection); exist {
+		for _, area := range c.Delegated(location) {
+			follow(area)
+		}
+	}
+
+	for {
+		var event domain.Event
+		select {
+		case <-ctx.Done():
+			return
+		case end := <-ended:
+			delete(following, end.key)
+			if end.delivered {
+				failures[end.area] = 0
+			}
+			backoff := min(max(n.settings.backoff, 100*time.Millisecond)<<failures[end.area], refollow)
+			failures[end.area] = min(failures[end.area]+1, 16)
+			time.AfterFunc(backoff, func() {
+				select {
+				case retry <- end.area:
+				case <-ctx.Done():
+				}
+			})
+			continue
+		case area := <-retry:
+			follow(area)
+			continue
+		case e, ok := <-local:
+			if !ok {
+				return
+			}
+			if e.Type == domain.Removed {
+				follow(e.Area)
+			}
+			event = e
+		case event = <-remote:
+		}
+
+		// A subscriber falling behind is dropped
+		select {
+		case out <- event:
+		default:
+			return
+		}
+	}
+}
+
+// publish notifies the subscriptions to the items of their change, and marks
+// the sets holding them to be pushed to their watchers.
+func (n *Node) publish(kind, area, owner string, items ...*domain.Item) {
+	for _, item := range items {
+		n.watchers.Touch(item.Collection, item.Location)
+		n.hub.Publish(domain.Event{Type: kind, Item: item, Area: area, Node: n.Name(), Owner: owner, Time: time.Now()})
+	}
+}
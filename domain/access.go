@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// Access modes of a collection, open when unset.
+const (
+	Open      = "open"
+	OwnerOnly = "owner"
+	Allowlist = "allowlist"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// Authorize checks that the item may be written in a collection of the
+// policy: any item in an open collection, otherwise only the items signed by
+// the owner or, with an allowlist, by one of the writers.
+func (p Policy) Authorize(item *Item) error {
+	writers := []ed25519.PublicKey{p.Owner}
+	switch p.Access {
+	case "", Open:
+		return nil
+	case OwnerOnly:
+	case Allowlist:
+		writers = append(writers, p.Writers...)
+	default:
+		return fmt.Errorf("%w: unknown access %q", ErrForbidden, p.Access)
+	}
+
+	if len(p.Owner) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: collection %s has no owner", ErrForbidden, item.Collection)
+	}
+	if !item.Signed() {
+		return fmt.Errorf("%w: item is not signed by its writer", ErrForbidden)
+	}
+	for _, writer := range writers {
+		if bytes.Equal(writer, item.Writer) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: writer is not allowed in collection %s", ErrForbidden, item.Collection)
+}
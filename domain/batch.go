@@ -0,0 +1,68 @@
+package domain
+
+import "errors"
+
+// Insertion is an item sent to a node with the policy of its collection, the
+// area it is inserted from and the nodes it went through.
+type Insertion struct {
+	Item    *Item    `json:"item"`
+	Policy  Policy   `json:"policy"`
+	Root    string   `json:"root,omitempty"`
+	Current string   `json:"current,omitempty"`
+	Hops    []string `json:"hops,omitempty"`
+}
+
+// Kinds of the errors answered to a peer, telling the error wrapped.
+var kinds = map[string]error{
+	"invalid":   ErrInvalid,
+	"forbidden": ErrForbidden,
+	"full":      ErrFull,
+	"hops":      ErrHops,
+	"pending":   ErrPending,
+	"unchanged": ErrNotModified,
+	// Refusals of the request itself rather than of the item
+	"unauthorized": ErrUnauthorized,
+	"limited":      ErrRateLimited,
+	"signature":    ErrSignature,
+}
+
+// Kind returns the kind of the error sent to a peer, empty for nil.
+func Kind(err error) string {
+	if err == nil {
+		return ""
+	}
+	for kind, target := range kinds {
+		if errors.Is(err, target) {
+			return kind
+		}
+	}
+	return "error"
+}
+
+type remoteError struct {
+	kind    error
+	message string
+}
+
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.kind
+}
+
+// KindError rebuilds an error received from a peer, nil for an empty kind.
+func KindError(kind, message string) error {
+	if len(kind) == 0 {
+		return nil
+	}
+	return &remoteError{kind: kinds[kind], message: message}
+}
+
+// Answered reports whether the error was answered by a peer, which was
+// reached, rather than a failure to reach it.
+func Answered(err error) bool {
+	var remote *remoteError
+	return errors.As(err, &remote)
+}
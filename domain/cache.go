@@ -1,71 +1,276 @@
 package domain
 
 import (
+	"container/list"
 	"sync"
 	"time"
 )
 
+// Cache keeps the sets of the areas owned by other nodes, nil for the areas
+// found empty. It holds up to capacity entries and size approximate bytes, the
+// least recently used entries being evicted first. A set expires once unused
+// for the positive ttl, an empty area once cached for the negative ttl. The
+// sets kept up to date by the pushes of their owners are polled once per
+// lease only.
 type Cache struct {
-	mu          *sync.Mutex
-	collections map[string]map[string]*Set
+	mu       *sync.Mutex
+	capacity int
+	size     int
+	positive time.Duration
+	negative time.Duration
+	lease    time.Duration
+	bytes    int
+	entries  map[Key]*list.Element
+	order    *list.List
+	stats    CacheStats
 }
 
-func NewCache() *Cache {
+// CacheStats are the figures of the cache published on the monitoring server.
+type CacheStats struct {
+	Entries     int `json:"entries"`
+	Bytes       int `json:"bytes"`
+	Hits        int `json:"hits"`
+	Misses      int `json:"misses"`
+	Evictions   int `json:"evictions"`
+	Expirations int `json:"expirations"`
+}
+
+type cached struct {
+	key    Key
+	set    *Set
+	bytes  int
+	until  time.Time
+	polled time.Time
+	pushed bool
+}
+
+// NewCache bounds the cache to capacity entries and size bytes, 0 for no
+// bound.
+func NewCache(capacity, size int, positive, negative, lease time.Duration) *Cache {
 	return &Cache{
-		mu:          &sync.Mutex{},
-		collections: make(map[string]map[string]*Set),
+		mu:       &sync.Mutex{},
+		capacity: capacity,
+		size:     size,
+		positive: positive,
+		negative: negative,
+		lease:    lease,
+		entries:  make(map[Key]*list.Element),
+		order:    list.New(),
 	}
 }
 
-func (c *Cache) Refresh(expiration time.Duration) map[string]map[string]any {
+// Refresh drops the expired entries and returns the areas to poll, the sets
+// pushed by their owners being polled once their lease is over only.
+func (c *Cache) Refresh() map[string]map[string]any {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	result := make(map[string]map[string]any)
 
-	for collection, sets := range c.collections {
-		result[collection] = make(map[string]any)
-		for location, set := range sets {
-			if set != nil && set.Expired(expiration) {
-				delete(sets, location)
-				continue
-			}
-			result[collection][location] = nil
+	now := time.Now()
+	for key, element := range c.entries {
+		entry := element.Value.(*cached)
+		if now.After(entry.until) {
+			c.remove(element)
+			c.stats.Expirations++
+			continue
+		}
+		if c.fresh(entry, now) {
+			continue
 		}
-		if len(sets) == 0 {
-			delete(c.collections, collection)
+		if _, exist := result[key.Collection]; !exist {
+			result[key.Collection] = make(map[string]any)
 		}
+		result[key.Collection][key.Location] = nil
 	}
 
 	return result
 }
 
+// Get returns the cached set of the area, nil for an area found empty, and
+// whether it is cached. A set found is kept for the positive ttl again.
 func (c *Cache) Get(collection, location string) (*Set, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	sets, exist := c.collections[collection]
+	element, exist := c.entries[Key{Collection: collection, Location: location}]
 	if !exist {
+		c.stats.Misses++
 		return nil, false
 	}
 
-	set, exist := sets[location]
-	if exist && set != nil {
-		set.Reset()
+	entry := element.Value.(*cached)
+	if time.Now().After(entry.until) {
+		c.remove(element)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
 	}
 
-	return set, exist
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	if entry.set != nil {
+		entry.until = time.Now().Add(c.positive)
+	}
+	return entry.set, true
 }
 
+// Set caches the set of the area polled from its owner, nil when it is
+// empty, and evicts the least recently used entries beyond the bounds of the
+// cache. A set replacing another keeps its expiry, only reads keeping it
+// cached.
 func (c *Cache) Set(collection, location string, set *Set) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, exist := c.collections[collection]; !exist {
-		c.collections[collection] = make(map[string]*Set)
+	key := Key{Collection: collection, Location: location}
+	if element, exist := c.entries[key]; exist {
+		entry := element.Value.(*cached)
+		if entry.set != nil && set != nil {
+			entry.polled = time.Now()
+			if entry.set.Version() != set.Version() {
+				c.replace(entry, set)
+			}
+			return
+		}
+		c.remove(element)
+	}
+
+	entry := &cached{key: key, set: set, bytes: weight(key, set), until: time.Now().Add(c.negative), polled: time.Now()}
+	if set != nil {
+		entry.until = time.Now().Add(c.positive)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.bytes += entry.bytes
+	c.evict()
+}
+
+// Apply changes the cached set of the area by the delta polled from its owner
+// and returns the set, nil when the delta does not follow the cached version.
+func (c *Cache) Apply(collection, location string, delta *Delta) *Set {
+	if delta.Full() {
+		set := NewSet()
+		set.Apply(delta)
+		c.Set(collection, location, set)
+		return set
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exist := c.entries[Key{Collection: collection, Location: location}]
+	if !exist || element.Value.(*cached).set == nil {
+		return nil
+	}
+
+	entry := element.Value.(*cached)
+	set := entry.set.Copy()
+	if !set.Apply(delta) {
+		return nil
+	}
+	entry.polled = time.Now()
+	c.replace(entry, set)
+	return set
+}
+
+// Version returns the version of the cached set of the area, 0 when none is
+// cached.
+func (c *Cache) Version(collection, location string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exist := c.entries[Key{Collection: collection, Location: location}]
+	if !exist || element.Value.(*cached).set == nil {
+		return 0
 	}
+	return element.Value.(*cached).set.Version()
+}
+
+// Unchanged records that the cached set of the area was polled and found at
+// the same version.
+func (c *Cache) Unchanged(collection, location string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, exist := c.entries[Key{Collection: collection, Location: location}]; exist {
+		element.Value.(*cached).polled = time.Now()
+	}
+}
+
+// Push replaces the cached set of the area by the set pushed by its owner,
+// nil dropping it so that it is fetched again, and reports whether the area
+// was cached.
+func (c *Cache) Push(collection, location string, set *Set) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exist := c.entries[Key{Collection: collection, Location: location}]
+	if !exist {
+		return false
+	}
+
+	entry := element.Value.(*cached)
+	if set == nil || entry.set == nil {
+		c.remove(element)
+		return true
+	}
+	entry.pushed = true
+	c.replace(entry, set)
+	return true
+}
+
+// Fresh reports whether the set of the area is kept up to date by its owner
+// and needs no polling.
+func (c *Cache) Fresh(collection, location string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exist := c.entries[Key{Collection: collection, Location: location}]
+	return exist && c.fresh(element.Value.(*cached), time.Now())
+}
+
+func (c *Cache) fresh(entry *cached, now time.Time) bool {
+	return entry.set != nil && entry.pushed && now.Before(entry.polled.Add(c.lease))
+}
+
+func (c *Cache) replace(entry *cached, set *Set) {
+	bytes := weight(entry.key, set)
+	c.bytes += bytes - entry.bytes
+	entry.set, entry.bytes = set, bytes
+	c.evict()
+}
+
+// evict removes the least recently used entries beyond the bounds.
+func (c *Cache) evict() {
+	for c.order.Len() > 1 && ((c.capacity > 0 && c.order.Len() > c.capacity) || (c.size > 0 && c.bytes > c.size)) {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+func (c *Cache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*cached)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.bytes
+}
 
-	if current, exist := c.collections[collection][location]; !exist || current == nil || set == nil || current.Count() != set.Count() {
-		c.collections[collection][location] = set
+// weight approximates the memory held by the entry of a set.
+func weight(key Key, set *Set) int {
+	bytes := 64 + len(key.Collection) + len(key.Location)
+	if set != nil {
+		set.Traverse(func(value string, _ int) {
+			bytes += 24 + len(value)
+		})
 	}
+	return bytes
 }
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsTheLeastRecentlyUsedEntries(t *testing.T) {
+	cache := NewCache(2, 0, time.Minute, time.Minute, time.Minute)
+
+	cache.Set("c", "a", NewSet())
+	cache.Set("c", "b", NewSet())
+	if _, exist := cache.Get("c", "a"); !exist {
+		t.Fatal("a is not cached")
+	}
+	cache.Set("c", "c", nil)
+
+	if _, exist := cache.Get("c", "b"); exist {
+		t.Error("least recently used entry b not evicted")
+	}
+	if _, exist := cache.Get("c", "a"); !exist {
+		t.Error("recently used entry a evicted")
+	}
+	if set, exist := cache.Get("c", "c"); !exist || set != nil {
+		t.Errorf("empty area c cached as %v, %t", set, exist)
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Evictions != 1 || stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestCacheExpiresTheEmptyAreasAfterTheNegativeTtl(t *testing.T) {
+	cache := NewCache(0, 0, time.Minute, time.Millisecond, time.Minute)
+
+	cache.Set("c", "a", NewSet())
+	cache.Set("c", "b", nil)
+	time.Sleep(5 * time.Millisecond)
+
+	refresh := cache.Refresh()
+	if _, exist := refresh["c"]["a"]; !exist {
+		t.Error("cached set a not polled")
+	}
+	if _, exist := refresh["c"]["b"]; exist {
+		t.Error("expired area b polled")
+	}
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Expirations != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestCacheAppliesTheDeltasFollowingTheCachedVersion(t *testing.T) {
+	cache := NewCache(0, 0, time.Minute, time.Minute, time.Minute)
+
+	source := NewSet()
+	source.Put("a", 1)
+	cache.Apply("c", "l", source.Since(0))
+	if version := cache.Version("c", "l"); version != source.Version() {
+		t.Fatalf("cached version %d, want %d", version, source.Version())
+	}
+
+	version := source.Version()
+	source.Put("b", 2)
+	set := cache.Apply("c", "l", source.Since(version))
+	if set == nil || set.Version() != source.Version() || set.List()["b"] != 2 {
+		t.Fatalf("delta applied as %v", set)
+	}
+
+	// A delta since another version leaves the cached set unchanged
+	if set := cache.Apply("c", "l", &Delta{Since: version, Version: version + 1}); set != nil {
+		t.Errorf("stale delta applied as %v", set.List())
+	}
+	if version := cache.Version("c", "l"); version != source.Version() {
+		t.Errorf("cached version %d, want %d", version, source.Version())
+	}
+}
+
+func TestCachePushedSetsAreFreshForTheLease(t *testing.T) {
+	cache := NewCache(0, 0, time.Minute, time.Minute, time.Minute)
+
+	if cache.Push("c", "a", NewSet()) {
+		t.Error("set pushed for an area not cached")
+	}
+	cache.Set("c", "a", NewSet())
+	if cache.Fresh("c", "a") {
+		t.Error("polled set is fresh")
+	}
+	if !cache.Push("c", "a", NewSet()) || !cache.Fresh("c", "a") {
+		t.Error("pushed set is not fresh")
+	}
+	if _, exist := cache.Refresh()["c"]["a"]; exist {
+		t.Error("fresh set polled")
+	}
+
+	// Pushing nil drops the set so that it is fetched again
+	if !cache.Push("c", "a", nil) {
+		t.Fatal("area not cached")
+	}
+	if _, exist := cache.Get("c", "a"); exist {
+		t.Error("set dropped by its owner still cached")
+	}
+}
@@ -52,18 +52,22 @@ func (c *Collections) List() []*Collection {
 }
 
 type Collection struct {
-	name  string
-	sets  map[string]*Set
-	owned Ownership
-	mu    *sync.Mutex
+	name   string
+	policy Policy
+	sets   map[string]*Set
+	owned  Ownership
+	signed map[string]*Item
+	mu     *sync.Mutex
 }
 
-func NewCollection(name string, root string) *Collection {
+func NewCollection(name string, root string, policy Policy) *Collection {
 	return &Collection{
-		name:  name,
-		sets:  map[string]*Set{root: NewSet()},
-		owned: map[string]Delegation{root: {}},
-		mu:    &sync.Mutex{},
+		name:   name,
+		policy: policy,
+		sets:   map[string]*Set{root: NewSet()},
+		owned:  map[string]Delegation{root: {}},
+		signed: make(map[string]*Item),
+		mu:     &sync.Mutex{},
 	}
 }
 
@@ -71,17 +75,45 @@ func (c *Collection) Name() string {
 	return c.name
 }
 
+func (c *Collection) Policy() Policy {
+	return c.policy
+}
+
 func (c *Collection) Allowing(location string) bool {
+	_, allowed := c.Area(location)
+	return allowed
+}
+
+// Area returns the area owned by the node the location belongs to, false when
+// the location is delegated or out of the areas of the node.
+func (c *Collection) Area(location string) (string, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	for child, parent := "", location; parent != ""; child, parent = parent, Parent(parent) {
 		if delegation, owned := c.owned[parent]; owned {
 			_, delegated := delegation[child]
-			return !delegated
+			return parent, !delegated
 		}
 	}
-	return false
+	return "", false
+}
+
+// Delegated returns the areas delegated by the node at the location, below
+// it or holding it.
+func (c *Collection) Delegated(location string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	areas := make([]string, 0)
+	for _, delegation := range c.owned {
+		for area := range delegation {
+			if Within(area, location) || Within(location, area) {
+				areas = append(areas, area)
+			}
+		}
+	}
+	return areas
 }
 
 func (c *Collection) Browse(processOwnership func(string), processDelegation func(string, string)) {
@@ -116,12 +148,19 @@ func (c *Collection) List() map[string]*Set {
 	return c.sets
 }
 
-func (c *Collection) Add(location, id string, setLength, delegation int) Ownership {
+func (c *Collection) Add(item *Item) Ownership {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	setLength, delegation := c.policy.SetLength, c.policy.Delegation
+
 	added, areas := false, Ownership{}
-	entry := fmt.Sprintf("%s:%s", location, id)
+	location, entry := item.Location, fmt.Sprintf("%s:%s", item.Location, item.Id)
+
+	// Signatures are kept to be handed over with the item
+	if len(item.Signature) > 0 {
+		c.signed[entry] = item
+	}
 
 	child, parent := "", location
 
@@ -157,19 +196,21 @@ func (c *Collection) Add(location, id string, setLength, delegation int) Ownersh
 	return areas
 }
 
-func (c *Collection) Update(location, sublocation string, count int) {
+// Update sets the count of the sublocation in the set of the location and in
+// its parents, and reports whether it changed.
+func (c *Collection) Update(location, sublocation string, count int) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	set, exist := c.sets[location]
 	if !exist {
-		return
+		return false
 	}
 
 	previous, _ := set.Get(sublocation)
 	delta := count - previous
 	if delta == 0 {
-		return
+		return false
 	}
 
 	set.Put(sublocation, count)
@@ -180,7 +221,7 @@ func (c *Collection) Update(location, sublocation string, count int) {
 
 		set, ok := c.sets[parent]
 		if !ok {
-			return
+			return true
 		}
 
 		set.Incr(child, delta)
@@ -239,7 +280,9 @@ func (c *Collection) Delegate(location string) ([]*Item, bool) {
 	c.traverse(location, func(set string, count int) {
 		delete(c.sets, set)
 	}, func(parent, location, id string) {
-		items = append(items, &Item{Collection: c.name, Location: location, Id: id})
+		item := c.item(location, id)
+		delete(c.signed, fmt.Sprintf("%s:%s", location, id))
+		items = append(items, item)
 	})
 
 	_, exist := c.owned[Parent(location)]
@@ -252,6 +295,25 @@ func (c *Collection) Delegate(location string) ([]*Item, bool) {
 	return items, len(c.owned) == 0
 }
 
+// Items returns the items of the area, with their signatures.
+func (c *Collection) Items(location string) []*Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	items := make([]*Item, 0)
+	c.traverse(location, func(string, int) {}, func(parent, location, id string) {
+		items = append(items, c.item(location, id))
+	})
+	return items
+}
+
+func (c *Collection) item(location, id string) *Item {
+	if item, exist := c.signed[fmt.Sprintf("%s:%s", location, id)]; exist {
+		return item
+	}
+	return &Item{Collection: c.name, Location: location, Id: id}
+}
+
 func (c *Collection) Traverse(parent string, processSet func(string, int), processItem func(string, string, string)) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -1,5 +1,7 @@
 package domain
 
+import "context"
+
 type Contact interface {
 	Peer
 
@@ -7,13 +9,20 @@ type Contact interface {
 	Port() int
 	IP() string
 	Host() string
+	Load() Load
 
-	Ping(Contact) (Contact, error)
-	Neighbors(Peer) ([]Contact, error)
-	Random(Peer) (Contact, error)
-	Transfer(Peer, Key, []*Item) error
-	Get(string, string) (Contact, *Set, error)
-	New(*Item, string, string) error
+	Ping(context.Context, Contact) (Contact, error)
+	Neighbors(context.Context, Peer) ([]Contact, error)
+	Random(context.Context, Peer) (Contact, error)
+	Transfer(context.Context, Peer, Key, Policy, []*Item, []Witness, []Redirect) error
+	Adopt(context.Context, Peer, Key, Policy, []*Item, []Witness, []Redirect) error
+	Get(context.Context, string, string, uint64) (Contact, *Set, error)
+	Changes(context.Context, string, string, uint64) (Contact, *Delta, error)
+	Invalidate(context.Context, Peer, Key, *Set) error
+	New(context.Context, *Item, Policy, string, string, []string) error
+	Insert(context.Context, *Item, Policy, string, string, []string) (Contact, string, error)
+	Batch(context.Context, []*Insertion) ([]error, error)
+	Subscribe(context.Context, string, string, []string) (<-chan Event, error)
 }
 
 func ConvertToContactSlice[T Contact](items []T) []Contact {
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrHops = errors.New("too many hops")
+
+// Letter is an item the node gave up inserting, with the reason and the
+// nodes it went through.
+type Letter struct {
+	Id       uint64    `json:"id"`
+	Item     *Item     `json:"item"`
+	Policy   Policy    `json:"policy"`
+	Root     string    `json:"root"`
+	Current  string    `json:"current"`
+	Hops     []string  `json:"hops"`
+	Attempts int       `json:"attempts"`
+	Reason   string    `json:"reason"`
+	Time     time.Time `json:"time"`
+	// Journal is the sequence of the letter in the journal of the node
+	Journal uint64 `json:"-"`
+}
+
+// DeadLetters keeps the last letters, up to capacity.
+type DeadLetters struct {
+	mu       *sync.Mutex
+	next     uint64
+	letters  []*Letter
+	capacity int
+}
+
+func NewDeadLetters(capacity int) *DeadLetters {
+	return &DeadLetters{
+		mu:       &sync.Mutex{},
+		letters:  make([]*Letter, 0),
+		capacity: capacity,
+	}
+}
+
+// Add records the letter under a new id, dropping the oldest one when full,
+// which is returned.
+func (d *DeadLetters) Add(letter Letter) *Letter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.next++
+	letter.Id = d.next
+	var dropped *Letter
+	if len(d.letters) >= d.capacity {
+		dropped = d.letters[0]
+		d.letters = d.letters[1:]
+	}
+	d.letters = append(d.letters, &letter)
+	return dropped
+}
+
+func (d *DeadLetters) List() []Letter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	letters := make([]Letter, 0, len(d.letters))
+	for _, letter := range d.letters {
+		letters = append(letters, *letter)
+	}
+	return letters
+}
+
+func (d *DeadLetters) Remove(id uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, letter := range d.letters {
+		if letter.Id == id {
+			d.letters = append(d.letters[:i], d.letters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
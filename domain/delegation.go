@@ -1,5 +1,7 @@
 package domain
 
+import "crypto/ed25519"
+
 type Key struct {
 	Collection string
 	Location   string
@@ -13,3 +15,31 @@ const delegation = 1_000
 func DelegationTreshold() int {
 	return delegation
 }
+
+// Policy holds the configuration of a collection, fixed when the collection
+// is created and propagated to every node owning part of it.
+type Policy struct {
+	Delegation int                 `json:"delegation,omitempty"`
+	SetLength  int                 `json:"setLength,omitempty"`
+	Access     string              `json:"access,omitempty"`
+	Owner      ed25519.PublicKey   `json:"owner,omitempty"`
+	Writers    []ed25519.PublicKey `json:"writers,omitempty"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Delegation: delegation,
+		SetLength:  idLength,
+	}
+}
+
+// WithDefaults returns the policy where every unset value is taken from fallback.
+func (p Policy) WithDefaults(fallback Policy) Policy {
+	if p.Delegation <= 0 {
+		p.Delegation = fallback.Delegation
+	}
+	if p.SetLength <= 0 {
+		p.SetLength = fallback.SetLength
+	}
+	return p
+}
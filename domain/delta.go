@@ -0,0 +1,93 @@
+package domain
+
+// journal is the number of changes of a set kept to answer deltas.
+const journal = 64
+
+type change struct {
+	version uint64
+	key     string
+}
+
+// Delta is the change of a set since a version, the counts of the entries
+// added or changed and the entries removed. A delta since 0 holds the whole
+// set, as when the version is older than the journal of the set.
+type Delta struct {
+	Since   uint64         `json:"since,omitempty"`
+	Version uint64         `json:"version"`
+	Changes map[string]int `json:"changes"`
+	Removed []string       `json:"removed,omitempty"`
+}
+
+// Full reports whether the delta holds the whole set.
+func (d *Delta) Full() bool {
+	return d.Since == 0
+}
+
+// Since returns the delta of the set since the version.
+func (s *Set) Since(version uint64) *Delta {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delta := &Delta{Version: s.version, Changes: make(map[string]int)}
+	if version < s.base || version > s.version {
+		for key, count := range s.list {
+			delta.Changes[key] = count
+		}
+		return delta
+	}
+
+	delta.Since = version
+	removed := make(map[string]any)
+	for _, change := range s.changes {
+		if change.version <= version {
+			continue
+		}
+		if count, exist := s.list[change.key]; exist {
+			delta.Changes[change.key] = count
+		} else if _, seen := removed[change.key]; !seen {
+			removed[change.key] = nil
+			delta.Removed = append(delta.Removed, change.key)
+		}
+	}
+	return delta
+}
+
+// Apply changes the set by the delta, which must be full or since the version
+// of the set, and reports whether it applied.
+func (s *Set) Apply(delta *Delta) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if delta.Full() {
+		s.list = make(map[string]int, len(delta.Changes))
+		for key, count := range delta.Changes {
+			s.list[key] = count
+		}
+		s.version, s.base, s.changes = delta.Version, delta.Version, nil
+		return true
+	}
+	if delta.Since != s.version {
+		return false
+	}
+
+	s.version = delta.Version
+	for key, count := range delta.Changes {
+		s.list[key] = count
+		s.record(key)
+	}
+	for _, key := range delta.Removed {
+		delete(s.list, key)
+		s.record(key)
+	}
+	return true
+}
+
+// record writes the change of the key at the current version in the journal,
+// forgetting the oldest change beyond its length.
+func (s *Set) record(key string) {
+	s.changes = append(s.changes, change{version: s.version, key: key})
+	if len(s.changes) > journal {
+		s.base = s.changes[0].version
+		s.changes = s.changes[1:]
+	}
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the events of an area.
+const (
+	// Added items were added to the area of the node
+	Added = "add"
+	// Removed items left the area of the node, handed over to the owner
+	Removed = "remove"
+)
+
+// Event is a change of an area of a collection.
+type Event struct {
+	Type  string    `json:"type"`
+	Item  *Item     `json:"item"`
+	Area  string    `json:"area"`
+	Node  string    `json:"node"`
+	Owner string    `json:"owner,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// Hub dispatches the events to the subscriptions to their collection and
+// location prefix.
+type Hub struct {
+	mu            *sync.Mutex
+	next          uint64
+	subscriptions map[uint64]*subscription
+}
+
+type subscription struct {
+	collection string
+	location   string
+	events     chan Event
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		mu:            &sync.Mutex{},
+		subscriptions: make(map[uint64]*subscription),
+	}
+}
+
+// Subscribe returns the events of the items of the collection at the
+// location or below, and the function ending the subscription. A subscription
+// falling more than buffer events behind is ended, its channel being closed.
+func (h *Hub) Subscribe(collection, location string, buffer int) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.next++
+	id := h.next
+	s := &subscription{collection: collection, location: location, events: make(chan Event, buffer)}
+	h.subscriptions[id] = s
+
+	return s.events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, exist := h.subscriptions[id]; exist {
+			delete(h.subscriptions, id)
+			close(s.events)
+		}
+	}
+}
+
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, s := range h.subscriptions {
+		if s.collection != event.Item.Collection || !Within(event.Item.Location, s.location) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			delete(h.subscriptions, id)
+			close(s.events)
+		}
+	}
+}
+
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscriptions)
+}
+
+// Within reports whether the location is the area or below it.
+func Within(location, area string) bool {
+	return area == root || strings.HasPrefix(location, area)
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	Healthy   = "healthy"
+	Suspected = "suspected"
+	Dead      = "dead"
+)
+
+// Record is the health of a peer as seen by the node.
+type Record struct {
+	Status   string    `json:"status"`
+	Failures int       `json:"failures"`
+	Retry    time.Time `json:"retry"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Health tracks the failed calls to every peer. A peer is suspected after
+// a failure and dead once the failures reach the threshold. The circuit of a
+// suspected peer stays open, no call being allowed, for a cooldown doubled
+// on every failure.
+type Health struct {
+	mu        *sync.Mutex
+	peers     map[string]*Record
+	threshold int
+	cooldown  time.Duration
+}
+
+func NewHealth(threshold int, cooldown time.Duration) *Health {
+	return &Health{
+		mu:        &sync.Mutex{},
+		peers:     make(map[string]*Record),
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether the circuit of the peer is closed or half-open.
+func (h *Health) Allow(peer Peer) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	record, exist := h.peers[peer.Name()]
+	return !exist || time.Now().After(record.Retry)
+}
+
+func (h *Health) Success(peer Peer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.peers, peer.Name())
+}
+
+// Failure records a failed call and opens the circuit of the peer.
+func (h *Health) Failure(peer Peer, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	record, exist := h.peers[peer.Name()]
+	if !exist {
+		record = &Record{}
+		h.peers[peer.Name()] = record
+	}
+
+	record.Failures++
+	record.Status = Suspected
+	if record.Failures >= h.threshold {
+		record.Status = Dead
+	}
+	record.Retry = time.Now().Add(h.cooldown << min(record.Failures-1, 6))
+	if err != nil {
+		record.Error = err.Error()
+	}
+}
+
+func (h *Health) Dead(peer Peer) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	record, exist := h.peers[peer.Name()]
+	return exist && record.Status == Dead
+}
+
+func (h *Health) Forget(peer Peer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.peers, peer.Name())
+}
+
+func (h *Health) List() map[string]Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := make(map[string]Record)
+	for name, record := range h.peers {
+		result[name] = *record
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Window remembers the area each idempotency key was inserted in, for ttl
+// and up to capacity keys, the oldest being forgotten first.
+type Window struct {
+	mu       *sync.Mutex
+	ttl      time.Duration
+	capacity int
+	areas    map[string]string
+	order    []witness
+}
+
+type witness struct {
+	key  string
+	time time.Time
+}
+
+// Witness is a key remembered by a window, with the area its item was
+// inserted in and when, as persisted and handed over with the areas.
+type Witness struct {
+	Key  string    `json:"key"`
+	Area string    `json:"area"`
+	Time time.Time `json:"time"`
+}
+
+func NewWindow(capacity int, ttl time.Duration) *Window {
+	return &Window{
+		mu:       &sync.Mutex{},
+		ttl:      ttl,
+		capacity: capacity,
+		areas:    make(map[string]string),
+		order:    make([]witness, 0),
+	}
+}
+
+// Seen returns the area the key was inserted in, false when it is unknown or
+// forgotten.
+func (w *Window) Seen(key string) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.forget()
+	area, seen := w.areas[key]
+	return area, seen
+}
+
+// Add remembers the area the key was inserted in.
+func (w *Window) Add(key, area string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.capacity <= 0 {
+		return
+	}
+	if _, seen := w.areas[key]; !seen {
+		w.order = append(w.order, witness{key: key, time: time.Now()})
+	}
+	w.areas[key] = area
+	w.forget()
+}
+
+// List returns the keys remembered, oldest first.
+func (w *Window) List() []Witness {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.forget()
+	witnesses := make([]Witness, 0, len(w.order))
+	for _, entry := range w.order {
+		witnesses = append(witnesses, Witness{Key: entry.key, Area: w.areas[entry.key], Time: entry.time})
+	}
+	return witnesses
+}
+
+// Put remembers the keys, with the time they were first inserted, unless
+// they are already known.
+func (w *Window) Put(witnesses ...Witness) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.capacity <= 0 {
+		return
+	}
+	for _, entry := range witnesses {
+		if _, seen := w.areas[entry.Key]; !seen {
+			w.order = append(w.order, witness{key: entry.Key, time: entry.Time})
+			w.areas[entry.Key] = entry.Area
+		}
+	}
+	sort.SliceStable(w.order, func(i, j int) bool { return w.order[i].time.Before(w.order[j].time) })
+	w.forget()
+}
+
+func (w *Window) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.forget()
+	return len(w.areas)
+}
+
+// forget drops the keys beyond the capacity or older than the ttl.
+func (w *Window) forget() {
+	expired := time.Now().Add(-w.ttl)
+	for len(w.order) > 0 && (len(w.order) > w.capacity || w.order[0].time.Before(expired)) {
+		delete(w.areas, w.order[0].key)
+		w.order = w.order[1:]
+	}
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowForgetsTheOldestKeysBeyondItsCapacity(t *testing.T) {
+	window := NewWindow(2, time.Minute)
+
+	window.Add("k1", "a")
+	window.Add("k2", "b")
+	window.Add("k1", "c")
+	if area, seen := window.Seen("k1"); !seen || area != "c" {
+		t.Errorf("k1 seen in %q, %t, want c", area, seen)
+	}
+
+	window.Add("k3", "d")
+	if _, seen := window.Seen("k1"); seen {
+		t.Error("oldest key k1 not forgotten")
+	}
+	if area, seen := window.Seen("k3"); !seen || area != "d" {
+		t.Errorf("k3 seen in %q, %t, want d", area, seen)
+	}
+	if window.Len() != 2 {
+		t.Errorf("window holds %d keys, want 2", window.Len())
+	}
+}
+
+func TestWindowForgetsTheKeysAfterTheTtl(t *testing.T) {
+	window := NewWindow(10, 10*time.Millisecond)
+
+	window.Add("k1", "a")
+	time.Sleep(20 * time.Millisecond)
+	if _, seen := window.Seen("k1"); seen {
+		t.Error("expired key k1 not forgotten")
+	}
+
+	disabled := NewWindow(0, time.Minute)
+	disabled.Add("k1", "a")
+	if _, seen := disabled.Seen("k1"); seen {
+		t.Error("window without capacity remembers k1")
+	}
+}
+
+func TestWindowPutKeepsTheTimeOfTheKeys(t *testing.T) {
+	now := time.Now()
+	source := NewWindow(10, time.Minute)
+	source.Put(
+		Witness{Key: "k2", Area: "b", Time: now.Add(-time.Second)},
+		Witness{Key: "k1", Area: "a", Time: now.Add(-2 * time.Second)},
+		Witness{Key: "old", Area: "c", Time: now.Add(-time.Hour)},
+	)
+
+	witnesses := source.List()
+	if len(witnesses) != 2 || witnesses[0].Key != "k1" || witnesses[1].Key != "k2" {
+		t.Fatalf("listed %+v, want k1 then k2 without the expired key", witnesses)
+	}
+
+	// The keys already known keep their area and time
+	target := NewWindow(10, time.Minute)
+	target.Add("k1", "z")
+	target.Put(witnesses...)
+	if area, _ := target.Seen("k1"); area != "z" {
+		t.Errorf("known key k1 moved to %q", area)
+	}
+	if area, seen := target.Seen("k2"); !seen || area != "b" {
+		t.Errorf("k2 seen in %q, %t, want b", area, seen)
+	}
+	if listed := target.List(); len(listed) != 2 || listed[0].Key != "k2" || !listed[0].Time.Equal(witnesses[1].Time) {
+		t.Errorf("listed %+v, want k2 first with its original time", listed)
+	}
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+)
+
+// Identity is the keypair of a node, its id is derived from the public key
+// and the nonce of its proof of work.
+type Identity struct {
+	public  ed25519.PublicKey
+	private ed25519.PrivateKey
+	nonce   uint64
+}
+
+func NewIdentity(difficulty int) (*Identity, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{public: public, private: private, nonce: Mine(public, 0, difficulty)}, nil
+}
+
+func IdentityFromKey(private ed25519.PrivateKey, nonce uint64) (*Identity, error) {
+	if len(private) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length: %d", len(private))
+	}
+	return &Identity{public: private.Public().(ed25519.PublicKey), private: private, nonce: nonce}, nil
+}
+
+// Harden mines a new nonce when the work of the proof is below the
+// difficulty, which changes the id, so that it is only done at the request
+// of the operator. It reports whether it did.
+func (i *Identity) Harden(difficulty int) bool {
+	if i.Proof().Work() >= difficulty {
+		return false
+	}
+	i.nonce = Mine(i.public, i.nonce+1, difficulty)
+	return true
+}
+
+func (i *Identity) Proof() *Proof {
+	return &Proof{Key: i.public, Nonce: i.nonce}
+}
+
+func (i *Identity) ID() []byte {
+	return i.Proof().ID()
+}
+
+func (i *Identity) Name() string {
+	return EncodeId(i.ID())
+}
+
+func (i *Identity) Public() ed25519.PublicKey {
+	return i.public
+}
+
+func (i *Identity) Private() ed25519.PrivateKey {
+	return i.private
+}
+
+func (i *Identity) Nonce() uint64 {
+	return i.nonce
+}
+
+func (i *Identity) Sign(message []byte) []byte {
+	return ed25519.Sign(i.private, message)
+}
@@ -1,13 +1,30 @@
 package domain
 
-import "fmt"
+import (
+	"crypto/ed25519"
+	"fmt"
+)
 
 type Item struct {
-	Collection string `json:"collection"`
-	Location   string `json:"location"`
-	Id         string `json:"id"`
+	Collection  string            `json:"collection"`
+	Location    string            `json:"location"`
+	Id          string            `json:"id"`
+	Writer      ed25519.PublicKey `json:"writer,omitempty"`
+	Signature   []byte            `json:"signature,omitempty"`
+	Idempotency string            `json:"idempotency,omitempty"`
 }
 
 func (i Item) Content() string {
 	return fmt.Sprintf("%s|%s|%s", i.Collection, i.Location, i.Id)
 }
+
+// Sign signs the content of the item with the key of its writer.
+func (i *Item) Sign(private ed25519.PrivateKey) {
+	i.Writer = private.Public().(ed25519.PublicKey)
+	i.Signature = ed25519.Sign(private, []byte(i.Content()))
+}
+
+// Signed reports whether the item carries a valid signature of its writer.
+func (i Item) Signed() bool {
+	return len(i.Writer) == ed25519.PublicKeySize && ed25519.Verify(i.Writer, []byte(i.Content()), i.Signature)
+}
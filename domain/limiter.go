@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("rate limited")
+
+// sweep is the number of buckets above which the full ones are dropped.
+const sweep = 4096
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket per key, refilled at rate tokens per second up
+// to burst tokens.
+type Limiter struct {
+	mu      *sync.Mutex
+	buckets map[string]*bucket
+	rate    float64
+	burst   float64
+}
+
+// NewLimiter returns a limiter of rate requests per second, nil when the rate
+// is not positive, which allows everything.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{
+		mu:      &sync.Mutex{},
+		buckets: make(map[string]*bucket),
+		rate:    rate,
+		burst:   math.Max(float64(burst), 1),
+	}
+}
+
+// Allow takes a token from the bucket of the key, otherwise it returns the
+// delay before the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.AllowN(key, 1)
+}
+
+// AllowN takes count tokens from the bucket of the key, otherwise it returns
+// the delay before they are available. A count above the burst needs a full
+// bucket and leaves it in debt, so that the rate holds over time.
+func (l *Limiter) AllowN(key string, count int) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if len(l.buckets) >= sweep {
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b, exist := l.buckets[key]
+	if !exist {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	need := math.Min(float64(max(count, 1)), l.burst)
+	if b.tokens < need {
+		return false, time.Duration((need - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens -= float64(max(count, 1))
+	return true, 0
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// Load holds the figures a node publishes to its peers with Ping.
+type Load struct {
+	Items int     `json:"items"`
+	Rate  float64 `json:"rate"`
+	Queue int     `json:"queue"`
+}
+
+// rateWeight counts an insertion per second as the items it adds in a minute.
+const rateWeight = 60
+
+// Score combines the figures into a number of items, the queued ones and
+// those the rate adds in a minute included.
+func (l Load) Score() float64 {
+	return float64(l.Items) + rateWeight*l.Rate + float64(l.Queue)
+}
+
+// Exceeds reports whether the score is above factor times the score of the
+// other load.
+func (l Load) Exceeds(other Load, factor float64) bool {
+	return l.Score() > factor*other.Score()
+}
+
+// Meter measures a rate of events per second over successive windows.
+type Meter struct {
+	mu    *sync.Mutex
+	count int
+	start time.Time
+	rate  float64
+}
+
+func NewMeter() *Meter {
+	return &Meter{
+		mu:    &sync.Mutex{},
+		start: time.Now(),
+	}
+}
+
+func (m *Meter) Mark() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count++
+}
+
+// Tick closes the current window and returns its rate.
+func (m *Meter) Tick() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elapsed := time.Since(m.start).Seconds(); elapsed > 0 {
+		m.rate = float64(m.count) / elapsed
+	}
+	m.count, m.start = 0, time.Now()
+
+	return m.rate
+}
+
+func (m *Meter) Rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rate
+}
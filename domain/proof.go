@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+var ErrProof = errors.New("invalid proof of work")
+
+// Proof derives the id of a node from its public key and a nonce. The work
+// of the proof is the number of leading zero bits of the hash of the id
+// hash, so that picking an id in a given region costs the work on top of
+// the search.
+type Proof struct {
+	Key   ed25519.PublicKey `json:"key"`
+	Nonce uint64            `json:"nonce"`
+}
+
+// Prover is implemented by the contacts carrying the proof of their id.
+type Prover interface {
+	Proof() *Proof
+}
+
+func (p *Proof) hash() [32]byte {
+	return sha256.Sum256(binary.BigEndian.AppendUint64(append([]byte{}, p.Key...), p.Nonce))
+}
+
+func (p *Proof) ID() []byte {
+	hash := p.hash()
+	return hash[:idLength]
+}
+
+func (p *Proof) Work() int {
+	hash := p.hash()
+	work := sha256.Sum256(hash[:])
+
+	zeros := 0
+	for _, b := range work {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return zeros
+}
+
+// Verify checks that the proof derives the name with at least the work of
+// the difficulty.
+func (p *Proof) Verify(name string, difficulty int) error {
+	id, err := DecodeName(name)
+	if err != nil || !bytes.Equal(id, p.ID()) {
+		return fmt.Errorf("%w: key does not match the name %s", ErrProof, name)
+	}
+	if work := p.Work(); work < difficulty {
+		return fmt.Errorf("%w: work %d of %s below difficulty %d", ErrProof, work, name, difficulty)
+	}
+	return nil
+}
+
+// Mine searches the first nonce from start giving the key the work of the
+// difficulty.
+func Mine(key ed25519.PublicKey, start uint64, difficulty int) uint64 {
+	proof := &Proof{Key: key, Nonce: start}
+	for proof.Work() < difficulty {
+		proof.Nonce++
+	}
+	return proof.Nonce
+}
+
+type proven struct {
+	Contact
+	proof *Proof
+}
+
+func (p *proven) Proof() *Proof {
+	return p.proof
+}
+
+// Prove attaches to the contact the proof received with its signature.
+func Prove(contact Contact, proof *Proof) Contact {
+	if proof == nil {
+		return contact
+	}
+	return &proven{Contact: contact, proof: proof}
+}
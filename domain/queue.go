@@ -1,26 +1,69 @@
 package domain
 
 import (
+	"errors"
 	"sync"
+	"time"
 )
 
+var ErrFull = errors.New("queue is full")
+
+// ErrPending tells that an item is queued but was not inserted in time.
+var ErrPending = errors.New("insertion pending")
+
+// Queue is a FIFO of at most capacity elements, unbounded when the capacity
+// is not positive.
 type Queue[T any] struct {
-	cursor int
-	mu     *sync.Mutex
-	cond   *sync.Cond
-	data   []T
+	cursor   int
+	capacity int
+	mu       *sync.Mutex
+	cond     *sync.Cond
+	freed    chan struct{}
+	data     []T
 }
 
-func NewQueue[T any]() *Queue[T] {
+func NewQueue[T any](capacity int) *Queue[T] {
 	mu := &sync.Mutex{}
 	return &Queue[T]{
-		mu:   mu,
-		cond: sync.NewCond(mu),
-		data: make([]T, 0),
+		capacity: capacity,
+		mu:       mu,
+		cond:     sync.NewCond(mu),
+		freed:    make(chan struct{}),
+		data:     make([]T, 0),
 	}
 }
 
-func (q *Queue[T]) Add(e T) {
+// Add appends the element, waiting up to wait for room when the queue is
+// full before failing with ErrFull.
+func (q *Queue[T]) Add(e T, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.capacity > 0 && len(q.data)-q.cursor >= q.capacity {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return ErrFull
+		}
+
+		freed := q.freed
+		q.mu.Unlock()
+		select {
+		case <-freed:
+		case <-time.After(remaining):
+		}
+		q.mu.Lock()
+	}
+
+	q.data = append(q.data, e)
+	q.cond.Signal()
+	return nil
+}
+
+// Put appends the element whatever the capacity, for the elements accepted
+// before, such as the ones replayed after a restart.
+func (q *Queue[T]) Put(e T) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
@@ -40,6 +83,11 @@ func (q *Queue[T]) Consume() (T, bool) {
 	result := q.data[q.cursor]
 	q.cursor++
 
+	if q.capacity > 0 {
+		close(q.freed)
+		q.freed = make(chan struct{})
+	}
+
 	return result, true
 }
 
@@ -49,3 +97,7 @@ func (q *Queue[T]) Length() int {
 
 	return len(q.data) - q.cursor
 }
+
+func (q *Queue[T]) Capacity() int {
+	return q.capacity
+}
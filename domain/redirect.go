@@ -0,0 +1,109 @@
+package domain
+
+import "sync"
+
+// Redirects records the areas placed on another node than the nearest one by
+// XOR distance, either handed to a peer or adopted from one.
+type Redirects struct {
+	mu   *sync.Mutex
+	data map[Key]Contact
+}
+
+// Redirect is an area handed to a peer, as handed over to the node resolving
+// the area next.
+type Redirect struct {
+	Key     Key
+	Contact Contact
+}
+
+func NewRedirects() *Redirects {
+	return &Redirects{
+		mu:   &sync.Mutex{},
+		data: make(map[Key]Contact),
+	}
+}
+
+func (r *Redirects) Get(key Key) (Contact, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	contact, exist := r.data[key]
+	return contact, exist
+}
+
+func (r *Redirects) Set(key Key, contact Contact) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.data[key] = contact
+}
+
+func (r *Redirects) Delete(key Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.data, key)
+}
+
+// Resolve returns the contact of the deepest redirected area containing the location.
+func (r *Redirects) Resolve(collection, location string) (Contact, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for current := location; current != ""; current = Parent(current) {
+		if contact, exist := r.data[Key{Collection: collection, Location: current}]; exist {
+			return contact, true
+		}
+	}
+	return nil, false
+}
+
+// Forget removes every redirect to the peer and returns the released areas.
+func (r *Redirects) Forget(peer Peer) []Key {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]Key, 0)
+	for key, contact := range r.data {
+		if contact.Name() == peer.Name() {
+			keys = append(keys, key)
+			delete(r.data, key)
+		}
+	}
+	return keys
+}
+
+// Below returns the redirects of the areas strictly below the area.
+func (r *Redirects) Below(key Key) []Redirect {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	redirects := make([]Redirect, 0)
+	for area, contact := range r.data {
+		if area.Collection == key.Collection && area.Location != key.Location && Within(area.Location, key.Location) {
+			redirects = append(redirects, Redirect{Key: area, Contact: contact})
+		}
+	}
+	return redirects
+}
+
+// Remove deletes the redirect of the area if it still goes to the contact.
+func (r *Redirects) Remove(key Key, contact Contact) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, exist := r.data[key]; exist && current.Name() == contact.Name() {
+		delete(r.data, key)
+	}
+}
+
+func (r *Redirects) List() map[Key]Contact {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make(map[Key]Contact)
+	for key, contact := range r.data {
+		result[key] = contact
+	}
+	return result
+}
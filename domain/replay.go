@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Replays remembers the signatures seen until they expire, up to capacity,
+// the oldest ones being forgotten first.
+type Replays struct {
+	mu       *sync.Mutex
+	seen     map[string]time.Time
+	order    []string
+	capacity int
+}
+
+func NewReplays(capacity int) *Replays {
+	return &Replays{
+		mu:       &sync.Mutex{},
+		seen:     make(map[string]time.Time),
+		order:    make([]string, 0),
+		capacity: max(capacity, 1),
+	}
+}
+
+// Check records the signature, failing when it was already seen. A signature
+// is remembered until its timestamp leaves the skew window, past which it
+// no longer verifies anyway.
+func (r *Replays) Check(signature *Signature) error {
+	key := signature.Name + "|" + strconv.FormatInt(signature.Timestamp, 10) + "|" + string(signature.Value)
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for len(r.order) > 0 {
+		expiry, exist := r.seen[r.order[0]]
+		if exist && now.Before(expiry) && len(r.order) < r.capacity {
+			break
+		}
+		delete(r.seen, r.order[0])
+		r.order = r.order[1:]
+	}
+
+	if _, exist := r.seen[key]; exist {
+		return fmt.Errorf("%w: message from %s replayed", ErrSignature, signature.Name)
+	}
+	r.seen[key] = time.Unix(signature.Timestamp, 0).Add(SignatureSkew)
+	r.order = append(r.order, key)
+	return nil
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReplaysRefuseASignatureSeenTwice(t *testing.T) {
+	identity, err := NewIdentity(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replays := NewReplays(2)
+
+	first, second := identity.Seal([]byte("first")), identity.Seal([]byte("second"))
+	if err := replays.Check(first); err != nil {
+		t.Fatalf("first signature refused: %v", err)
+	}
+	if err := replays.Check(first); !errors.Is(err, ErrSignature) {
+		t.Errorf("replayed signature answered %v, want %v", err, ErrSignature)
+	}
+	if err := replays.Check(second); err != nil {
+		t.Fatalf("second signature refused: %v", err)
+	}
+
+	// Beyond the capacity the oldest signatures are forgotten
+	if err := replays.Check(identity.Seal([]byte("third"))); err != nil {
+		t.Fatalf("third signature refused: %v", err)
+	}
+	if err := replays.Check(first); err != nil {
+		t.Errorf("forgotten signature refused: %v", err)
+	}
+}
@@ -1,24 +1,66 @@
 package domain
 
 import (
+	"errors"
 	"sync"
 	"time"
 )
 
+// ErrNotModified answers the fetch of a set whose version did not change.
+var ErrNotModified = errors.New("set not modified")
+
+// Set counts the items of an area by sub-area. Its version grows on every
+// change, starting from the creation time so that a set created again on the
+// same node never reuses the version of a previous one. The last changes are
+// kept in a journal, covering the versions above base.
 type Set struct {
-	list map[string]int
-	last time.Time
-	mu   *sync.Mutex
+	list    map[string]int
+	version uint64
+	base    uint64
+	changes []change
+	mu      *sync.Mutex
 }
 
 func NewSet() *Set {
+	version := uint64(time.Now().UnixNano())
 	return &Set{
-		list: make(map[string]int),
-		last: time.Now(),
-		mu:   &sync.Mutex{},
+		list:    make(map[string]int),
+		version: version,
+		base:    version,
+		mu:      &sync.Mutex{},
 	}
 }
 
+// Copy returns a copy of the set with its version and its journal.
+func (s *Set) Copy() *Set {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := NewSet()
+	for key, value := range s.list {
+		result.list[key] = value
+	}
+	result.version, result.base = s.version, s.base
+	result.changes = append([]change(nil), s.changes...)
+	return result
+}
+
+func (s *Set) Version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.version
+}
+
+// SetVersion sets the version of a set received from its owner, its journal
+// starting from there.
+func (s *Set) SetVersion(version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version, s.base, s.changes = version, version, nil
+}
+
 func (s *Set) List() map[string]int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -72,20 +114,6 @@ func (s *Set) Incr(value string, n int) int {
 	return s.incr(value, n)
 }
 
-func (s *Set) Reset() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.last = time.Now()
-}
-
-func (s *Set) Expired(expiration time.Duration) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	return time.Since(s.last) > expiration
-}
-
 func (s *Set) Shrink(sets map[string]*Set, key string, max int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -106,11 +134,17 @@ func (s *Set) get(value string) (int, bool) {
 
 func (s *Set) add(value string, max int) bool {
 	s.list[value] = 1
+	s.version++
+	s.record(value)
 	return len(s.list) > max
 }
 
 func (s *Set) put(value string, count int) {
-	s.list[value] = count
+	if current, exist := s.list[value]; !exist || current != count {
+		s.list[value] = count
+		s.version++
+		s.record(value)
+	}
 }
 
 func (s *Set) count() int {
@@ -124,6 +158,8 @@ func (s *Set) count() int {
 func (s *Set) incr(value string, n int) int {
 	result := s.list[value] + n
 	s.list[value] = result
+	s.version++
+	s.record(value)
 	return result
 }
 
@@ -170,5 +206,16 @@ func (s *Set) shrink(sets map[string]*Set, key string, max int) {
 		list[elm] = 1
 	}
 
+	s.version++
+	for key, count := range s.list {
+		if current, exist := list[key]; !exist || current != count {
+			s.record(key)
+		}
+	}
+	for key := range list {
+		if _, exist := s.list[key]; !exist {
+			s.record(key)
+		}
+	}
 	s.list = list
 }
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSetAppliesTheDeltaSinceItsVersion(t *testing.T) {
+	source := NewSet()
+	source.Put("a", 1)
+	source.Put("b", 2)
+
+	replica := NewSet()
+	if !replica.Apply(source.Since(0)) {
+		t.Fatal("full delta not applied")
+	}
+	if replica.Version() != source.Version() {
+		t.Fatalf("replica at version %d, want %d", replica.Version(), source.Version())
+	}
+
+	version := source.Version()
+	source.Put("b", 3)
+	source.Put("c", 1)
+	source.Apply(&Delta{Since: source.Version(), Version: source.Version() + 1, Removed: []string{"a"}})
+
+	delta := source.Since(version)
+	if delta.Full() {
+		t.Fatal("delta since a journaled version is full")
+	}
+	if len(delta.Changes) != 2 || delta.Changes["b"] != 3 || delta.Changes["c"] != 1 {
+		t.Errorf("delta changes %v, want b:3 c:1", delta.Changes)
+	}
+	if len(delta.Removed) != 1 || delta.Removed[0] != "a" {
+		t.Errorf("delta removed %v, want [a]", delta.Removed)
+	}
+
+	if !replica.Apply(delta) {
+		t.Fatal("delta since the version of the replica not applied")
+	}
+	if fmt.Sprint(replica.List()) != fmt.Sprint(source.List()) {
+		t.Errorf("replica holds %v, want %v", replica.List(), source.List())
+	}
+
+	// A delta which does not follow the version of the set is refused
+	if replica.Apply(delta) {
+		t.Error("delta applied twice")
+	}
+}
+
+func TestSetSinceAVersionBeyondTheJournalIsFull(t *testing.T) {
+	set := NewSet()
+	version := set.Version()
+	for i := 0; i <= journal; i++ {
+		set.Put(fmt.Sprint(i), 1)
+	}
+
+	if delta := set.Since(version); !delta.Full() || len(delta.Changes) != journal+1 {
+		t.Errorf("delta since a forgotten version holds %d changes since %d, want the whole set", len(delta.Changes), delta.Since)
+	}
+	if delta := set.Since(set.Version() + 1); !delta.Full() {
+		t.Error("delta since a version ahead of the set is not full")
+	}
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the signature of the peer messages sent over HTTP.
+const SignatureHeader = "X-Indexus-Signature"
+
+// SignatureSkew bounds the age of a signed message, older messages are
+// rejected to limit their replay.
+const SignatureSkew = 5 * time.Minute
+
+var ErrSignature = errors.New("invalid signature")
+
+// Signature proves that a message was sent by the node owning the key the
+// name is derived from, with the nonce of its proof of work.
+type Signature struct {
+	Name      string
+	Key       ed25519.PublicKey
+	Nonce     uint64
+	Timestamp int64
+	Value     []byte
+}
+
+// Seal signs the message with the identity of the node.
+func (i *Identity) Seal(message []byte) *Signature {
+	timestamp := time.Now().Unix()
+	return &Signature{
+		Name:      i.Name(),
+		Key:       i.public,
+		Nonce:     i.nonce,
+		Timestamp: timestamp,
+		Value:     ed25519.Sign(i.private, signed(timestamp, message)),
+	}
+}
+
+func (s *Signature) Verify(message []byte) error {
+	if len(s.Key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: invalid key length", ErrSignature)
+	}
+
+	id, err := DecodeName(s.Name)
+	if err != nil || !bytes.Equal(id, s.Proof().ID()) {
+		return fmt.Errorf("%w: key does not match the name %s", ErrSignature, s.Name)
+	}
+
+	age := time.Since(time.Unix(s.Timestamp, 0))
+	if age > SignatureSkew || age < -SignatureSkew {
+		return fmt.Errorf("%w: expired", ErrSignature)
+	}
+
+	if !ed25519.Verify(s.Key, signed(s.Timestamp, message), s.Value) {
+		return fmt.Errorf("%w: forged message from %s", ErrSignature, s.Name)
+	}
+	return nil
+}
+
+// Proof returns the proof of work of the signer.
+func (s *Signature) Proof() *Proof {
+	return &Proof{Key: s.Key, Nonce: s.Nonce}
+}
+
+func signed(timestamp int64, message []byte) []byte {
+	return append(binary.BigEndian.AppendUint64(nil, uint64(timestamp)), message...)
+}
+
+// String encodes the signature as name.key.nonce.timestamp.value, the key and
+// the value in base64.
+func (s *Signature) String() string {
+	return strings.Join([]string{
+		s.Name,
+		base64.RawURLEncoding.EncodeToString(s.Key),
+		strconv.FormatUint(s.Nonce, 10),
+		strconv.FormatInt(s.Timestamp, 10),
+		base64.RawURLEncoding.EncodeToString(s.Value),
+	}, ".")
+}
+
+func ParseSignature(value string) (*Signature, error) {
+	arr := strings.Split(value, ".")
+	if len(arr) != 5 {
+		return nil, fmt.Errorf("%w: malformed", ErrSignature)
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(arr[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed key", ErrSignature)
+	}
+
+	nonce, err := strconv.ParseUint(arr[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed nonce", ErrSignature)
+	}
+
+	timestamp, err := strconv.ParseInt(arr[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed timestamp", ErrSignature)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(arr[4])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed value", ErrSignature)
+	}
+
+	return &Signature{Name: arr[0], Key: key, Nonce: nonce, Timestamp: timestamp, Value: sig}, nil
+}
+
+// RequestMessage is the signed content of a request sent over HTTP.
+func RequestMessage(method, uri string, body []byte) []byte {
+	return append([]byte(method+" "+uri+"\n"), body...)
+}
+
+// ResponseMessage is the signed content of a response, bound to the signature
+// of the request.
+func ResponseMessage(request []byte, body []byte) []byte {
+	return append(append(append([]byte{}, request...), '\n'), body...)
+}
@@ -63,6 +63,9 @@ func DecodeLocation(universe, location string) ([]byte, error) {
 		key = location
 	}
 	if location != root {
+		if len(location) > len(key) {
+			return nil, invalid("location", "length is %d, more than %d", len(location), len(key))
+		}
 		key = location + key[len(location):]
 	}
 	return DecodeName(key)
@@ -6,5 +6,17 @@ type Storage interface {
 	Save([]string) error
 	Load() ([]string, error)
 	Append(string)
+	// Commit appends the log and returns once it is written
+	Commit(string) error
 	Stream(int) <-chan string
 }
+
+// Journal persists the elements of a queue until they are processed, so that
+// they are replayed after a crash.
+type Journal interface {
+	Append(uint64, []byte) error
+	Done(uint64) error
+	// Replay returns the data pending, in order, which stays journaled
+	// under the sequence numbers 1 to n
+	Replay() ([][]byte, error)
+}
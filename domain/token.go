@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Actions granted by the scopes of a token.
+const (
+	Read  = "read"
+	Write = "write"
+	// Admin registers the webhooks of a collection and replays dead letters
+	Admin = "admin"
+)
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Grant gives a client the scopes of its token, formatted as action:collection
+// where the action or the collection may be * for any.
+type Grant struct {
+	Name   string   `json:"name"`
+	Token  string   `json:"token"`
+	Scopes []string `json:"scopes"`
+}
+
+// Tokens holds the grants of the clients, indexed by the hash of their token.
+type Tokens struct {
+	grants map[[sha256.Size]byte]Grant
+}
+
+func NewTokens(grants []Grant) (*Tokens, error) {
+	t := &Tokens{grants: make(map[[sha256.Size]byte]Grant)}
+	for _, grant := range grants {
+		if len(grant.Token) == 0 {
+			return nil, fmt.Errorf("token of %s is empty", grant.Name)
+		}
+		for _, scope := range grant.Scopes {
+			if _, _, ok := strings.Cut(scope, ":"); !ok && scope != "*" {
+				return nil, fmt.Errorf("invalid scope of %s: %s", grant.Name, scope)
+			}
+		}
+		t.grants[sha256.Sum256([]byte(grant.Token))] = grant
+	}
+	return t, nil
+}
+
+// Valid reports whether the token is granted to a client.
+func (t *Tokens) Valid(token string) bool {
+	_, exist := t.grants[sha256.Sum256([]byte(token))]
+	return exist
+}
+
+// Allow checks that the token grants the action on the collection.
+func (t *Tokens) Allow(token, action, collection string) error {
+	grant, exist := t.grants[sha256.Sum256([]byte(token))]
+	if !exist {
+		return fmt.Errorf("%w: unknown token", ErrUnauthorized)
+	}
+	for _, scope := range grant.Scopes {
+		if scope == "*" {
+			return nil
+		}
+		a, c, _ := strings.Cut(scope, ":")
+		if (a == "*" || a == action) && (c == "*" || c == collection) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not allowed to %s %s", ErrForbidden, grant.Name, action, collection)
+}
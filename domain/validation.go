@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const maxItemId = 256
+
+const maxIdempotency = 256
+
+var ErrInvalid = errors.New("invalid request")
+
+// ValidationError tells which field of a request is invalid and why.
+type ValidationError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
+
+func invalid(field, reason string, args ...any) error {
+	return &ValidationError{Field: field, Reason: fmt.Sprintf(reason, args...)}
+}
+
+// ValidateName checks that the field is the encoding of an id, as the names
+// of the nodes and the collections are.
+func ValidateName(field, name string) error {
+	length := base.EncodedLen(idLength)
+	if len(name) != length {
+		return invalid(field, "length is %d instead of %d", len(name), length)
+	}
+	if i := strings.IndexFunc(name, outside); i >= 0 {
+		return invalid(field, "character %q is not in the alphabet", name[i])
+	}
+	if _, err := DecodeName(name); err != nil {
+		return invalid(field, "%v", err)
+	}
+	return nil
+}
+
+// ValidateLocation checks that the location is the root or a prefix of the
+// length of an id at most.
+func ValidateLocation(field, location string) error {
+	if location == root {
+		return nil
+	}
+	if len(location) == 0 {
+		return invalid(field, "location is empty, the root is %s", root)
+	}
+	if length := base.EncodedLen(idLength); len(location) > length {
+		return invalid(field, "length is %d, more than %d", len(location), length)
+	}
+	if i := strings.IndexFunc(location, outside); i >= 0 {
+		return invalid(field, "character %q is not in the alphabet", location[i])
+	}
+	return nil
+}
+
+// ValidateKey checks the collection and the location of an area.
+func ValidateKey(key Key) error {
+	if err := ValidateName("collection", key.Collection); err != nil {
+		return err
+	}
+	return ValidateLocation("location", key.Location)
+}
+
+// Validate checks the collection and the location of the item, the location
+// of an item being a full id, and that its id holds none of the separators
+// of the sets and the logs.
+func (i *Item) Validate() error {
+	if i == nil {
+		return invalid("item", "item is missing")
+	}
+	if err := ValidateName("collection", i.Collection); err != nil {
+		return err
+	}
+	if err := ValidateName("location", i.Location); err != nil {
+		return err
+	}
+	if len(i.Id) == 0 || len(i.Id) > maxItemId {
+		return invalid("id", "length is %d, not between 1 and %d", len(i.Id), maxItemId)
+	}
+	if strings.ContainsAny(i.Id, ":|") || strings.IndexFunc(i.Id, unicode.IsControl) >= 0 {
+		return invalid("id", "id holds a separator or a control character")
+	}
+	if len(i.Idempotency) > maxIdempotency {
+		return invalid("idempotency", "length is %d, more than %d", len(i.Idempotency), maxIdempotency)
+	}
+	if strings.IndexFunc(i.Idempotency, unicode.IsControl) >= 0 {
+		return invalid("idempotency", "key holds a control character")
+	}
+	if len(i.Writer) > 0 && len(i.Writer) != ed25519.PublicKeySize {
+		return invalid("writer", "key length is %d instead of %d", len(i.Writer), ed25519.PublicKeySize)
+	}
+	return nil
+}
+
+func outside(r rune) bool {
+	return !strings.ContainsRune(alphabet, r)
+}
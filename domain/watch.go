@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// Watchers tracks the peers caching the sets of the node, until ttl after
+// their last fetch, and the sets changed since they were last pushed.
+type Watchers struct {
+	mu      *sync.Mutex
+	ttl     time.Duration
+	watched map[Key]map[string]time.Time
+	changed map[Key]any
+}
+
+func NewWatchers(ttl time.Duration) *Watchers {
+	return &Watchers{
+		mu:      &sync.Mutex{},
+		ttl:     ttl,
+		watched: make(map[Key]map[string]time.Time),
+		changed: make(map[Key]any),
+	}
+}
+
+// Watch records that the peer fetched the set of the key. The set is pushed
+// to a new watcher once, telling it that it needs no polling.
+func (w *Watchers) Watch(key Key, name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exist := w.watched[key]; !exist {
+		w.watched[key] = make(map[string]time.Time)
+	}
+	if _, exist := w.watched[key][name]; !exist {
+		w.changed[key] = nil
+	}
+	w.watched[key][name] = time.Now().Add(w.ttl)
+}
+
+// Forget stops pushing the set of the key to the peer.
+func (w *Watchers) Forget(key Key, name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.watched[key], name)
+	if len(w.watched[key]) == 0 {
+		delete(w.watched, key)
+	}
+}
+
+// Touch marks the watched sets holding the location as changed.
+func (w *Watchers) Touch(collection, location string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key := range w.watched {
+		if key.Collection == collection && Within(location, key.Location) {
+			w.changed[key] = nil
+		}
+	}
+}
+
+// Drain returns the changed sets with the peers watching them, and forgets
+// the peers whose watch expired.
+func (w *Watchers) Drain() map[Key][]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	result := make(map[Key][]string)
+	for key := range w.changed {
+		for name, until := range w.watched[key] {
+			if now.After(until) {
+				delete(w.watched[key], name)
+				continue
+			}
+			result[key] = append(result[key], name)
+		}
+		if len(w.watched[key]) == 0 {
+			delete(w.watched, key)
+		}
+	}
+	w.changed = make(map[Key]any)
+	return result
+}
+
+// Expire forgets the peers whose watch expired.
+func (w *Watchers) Expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	for key, names := range w.watched {
+		for name, until := range names {
+			if now.After(until) {
+				delete(names, name)
+			}
+		}
+		if len(names) == 0 {
+			delete(w.watched, key)
+		}
+	}
+}
+
+func (w *Watchers) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	count := 0
+	for _, names := range w.watched {
+		count += len(names)
+	}
+	return count
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// WebhookSignatureHeader carries the signature of the deliveries of the
+// webhooks, and WebhookTimestampHeader the Unix time it covers.
+const (
+	WebhookSignatureHeader = "X-Indexus-Webhook-Signature"
+	WebhookTimestampHeader = "X-Indexus-Webhook-Timestamp"
+)
+
+// WebhookTolerance is the difference between the timestamp of a delivery and
+// the clock of the receiver beyond which the receiver should refuse it, as a
+// replayed one.
+const WebhookTolerance = 5 * time.Minute
+
+// Webhook posts the events of the items of a collection at a location or
+// below to a URL, signed with its secret.
+type Webhook struct {
+	Id         string `json:"id"`
+	Collection string `json:"collection"`
+	Location   string `json:"location"`
+	URL        string `json:"url"`
+	Secret     string `json:"secret,omitempty"`
+}
+
+// Validate checks the webhook, whose URL may only reach a loopback, link-local
+// or private address when private is true.
+func (w Webhook) Validate(private bool) error {
+	if err := ValidateKey(Key{Collection: w.Collection, Location: w.Location}); err != nil {
+		return err
+	}
+	target, err := url.Parse(w.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || len(target.Hostname()) == 0 {
+		return invalid("url", "%q is not an http or https URL", w.URL)
+	}
+	if !private && (target.Hostname() == "localhost" || Internal(net.ParseIP(target.Hostname()))) {
+		return invalid("url", "%q reaches an internal address", w.URL)
+	}
+	if len(w.Secret) == 0 {
+		return invalid("secret", "secret is empty")
+	}
+	return nil
+}
+
+// Internal reports whether the address is a loopback, link-local, private or
+// unspecified one, false for nil.
+func Internal(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// Sign returns the HMAC-SHA256 of "timestamp.payload" with the secret of the
+// webhook, as sent in the signature header.
+func (w Webhook) Sign(timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliveries is the state of the deliveries of a webhook.
+type Deliveries struct {
+	Delivered  int       `json:"delivered"`
+	Failed     int       `json:"failed"`
+	Dropped    int       `json:"dropped"`
+	Pending    int       `json:"pending"`
+	LastStatus int       `json:"lastStatus,omitempty"`
+	LastError  string    `json:"lastError,omitempty"`
+	LastTime   time.Time `json:"lastTime"`
+}
@@ -2,9 +2,11 @@ package monitoring
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/indexus/go-indexus-core/domain"
 )
@@ -15,10 +17,25 @@ type Service interface {
 	Routing() ([]domain.Contact, error)
 	Ownership() (map[string]map[string]map[string]any, error)
 	Queue() int
+	Load() domain.Load
+	Redirects() map[string]string
+	Cache() domain.CacheStats
+	Health() map[string]domain.Record
+	DeadLetters() []domain.Letter
+	Replay(...uint64) (int, error)
+}
+
+type Webhooks interface {
+	Add(domain.Webhook) (domain.Webhook, error)
+	Remove(string) (bool, error)
+	Webhooks() []domain.Webhook
+	Deliveries() map[string]domain.Deliveries
 }
 
 type Handler struct {
-	Service Service
+	Service  Service
+	webhooks Webhooks
+	tokens   *domain.Tokens
 }
 
 // New - Create a HTTP handler
@@ -28,6 +45,11 @@ func NewHttpHandler(service Service) *Handler {
 	}
 }
 
+// Hook serves the webhooks of the registry on the /webhooks endpoint.
+func (h *Handler) Hook(webhooks Webhooks) {
+	h.webhooks = webhooks
+}
+
 // Serve - Run the HTTP server
 func (h *Handler) Serve(lis net.Listener) error {
 
@@ -39,6 +61,12 @@ func (h *Handler) Serve(lis net.Listener) error {
 	mux.HandleFunc("/routing", h.Routing)
 	mux.HandleFunc("/ownership", h.Ownership)
 	mux.HandleFunc("/queue", h.Queue)
+	mux.HandleFunc("/load", h.Load)
+	mux.HandleFunc("/health", h.Health)
+	mux.HandleFunc("/cache", h.Cache)
+	mux.HandleFunc("/deadletters", h.DeadLetters)
+	mux.HandleFunc("/deadletters/replay", h.Replay)
+	mux.HandleFunc("/webhooks", h.Webhooks)
 
 	s := &http.Server{Handler: mux}
 
@@ -128,3 +156,123 @@ func (h *Handler) Queue(w http.ResponseWriter, r *http.Request) {
 		h.Service.Queue(),
 	})
 }
+
+// Load handles the /load endpoint
+func (h *Handler) Load(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Load      domain.Load       `json:"load"`
+		Redirects map[string]string `json:"redirects"`
+	}{
+		h.Service.Load(),
+		h.Service.Redirects(),
+	})
+}
+
+// Health handles the /health endpoint
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Peers map[string]domain.Record `json:"peers"`
+	}{
+		h.Service.Health(),
+	})
+}
+
+// Cache handles the /cache endpoint
+func (h *Handler) Cache(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Service.Cache())
+}
+
+// DeadLetters handles the /deadletters endpoint
+func (h *Handler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Letters []domain.Letter `json:"letters"`
+	}{
+		h.Service.DeadLetters(),
+	})
+}
+
+// Replay handles the /deadletters/replay endpoint
+func (h *Handler) Replay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		return
+	}
+	if !h.admin(w, r, "*") {
+		return
+	}
+
+	ids := make([]uint64, 0)
+	for _, value := range r.URL.Query()["id"] {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id: " + value})
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	count, err := h.Service.Replay(ids...)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": err.Error(), "replayed": count})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"replayed": count})
+}
+
+// Webhooks handles the /webhooks endpoint
+func (h *Handler) Webhooks(w http.ResponseWriter, r *http.Request) {
+	if h.webhooks == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhooks are disabled"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, struct {
+			Webhooks   []domain.Webhook             `json:"webhooks"`
+			Deliveries map[string]domain.Deliveries `json:"deliveries"`
+		}{
+			h.webhooks.Webhooks(),
+			h.webhooks.Deliveries(),
+		})
+
+	case http.MethodPost:
+		var webhook domain.Webhook
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&webhook); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid webhook: " + err.Error()})
+			return
+		}
+		if !h.admin(w, r, webhook.Collection) {
+			return
+		}
+		webhook, err := h.webhooks.Add(webhook)
+		if errors.Is(err, domain.ErrInvalid) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		webhook.Secret = ""
+		writeJSON(w, http.StatusCreated, webhook)
+
+	case http.MethodDelete:
+		if !h.admin(w, r, "*") {
+			return
+		}
+		removed, err := h.webhooks.Remove(r.URL.Query().Get("id"))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if !removed {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown webhook"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"removed": true})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET, POST or DELETE required"})
+	}
+}
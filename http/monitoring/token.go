@@ -0,0 +1,48 @@
+package monitoring
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// Authorize requires a bearer token granting the admin scope on the endpoints
+// changing the node. Without tokens, only loopback clients may change it.
+func (h *Handler) Authorize(tokens *domain.Tokens) {
+	h.tokens = tokens
+}
+
+var errBearer = errors.New("bearer token required")
+
+// admin checks that the request may administer the collection, * for the
+// whole node, and answers with an error if not.
+func (h *Handler) admin(w http.ResponseWriter, r *http.Request, collection string) bool {
+	if h.tokens == nil {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			return true
+		}
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only loopback clients may change the node without tokens"})
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": errBearer.Error()})
+		return false
+	}
+	err := h.tokens.Allow(token, domain.Admin, collection)
+	switch {
+	case errors.Is(err, domain.ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case err != nil:
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+	return err == nil
+}
@@ -0,0 +1,97 @@
+package p2p
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// budget is the rate limit applying to an endpoint.
+type budget int
+
+const (
+	reads budget = iota
+	writes
+	maintenance
+)
+
+// Limit sets the limiters of the reads and writes of the client endpoints and
+// of the peer endpoints, a nil limiter allowing everything.
+func (h *Handler) Limit(read, write, peer *domain.Limiter) {
+	h.limiters = [...]*domain.Limiter{reads: read, writes: write, maintenance: peer}
+}
+
+// Trust takes the forwarding headers of the requests coming from the proxies
+// for the address of the client.
+func (h *Handler) Trust(proxies []*net.IPNet) {
+	h.proxies = proxies
+}
+
+// limit applies the budget to the known peer which signed the request, or
+// else to the bearer token granted or the client IP, answering 429 once it is
+// exhausted. Other signers are limited by IP, as new keys are free.
+func (h *Handler) limit(b budget, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.charge(w, r, b, 1) {
+			next(w, r)
+		}
+	}
+}
+
+// charge takes count tokens from the budget of the client of the request, and
+// answers 429 if they are not available.
+func (h *Handler) charge(w http.ResponseWriter, r *http.Request, b budget, count int) bool {
+	key := "ip:" + h.remote(r)
+	if h.peer(r) {
+		key = "peer:" + signer(r).Name
+	} else if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && h.tokens != nil && h.tokens.Valid(token) {
+		key = "token:" + token
+	}
+
+	if ok, wait := h.limiters[b].AllowN(key, count); !ok {
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": domain.ErrRateLimited.Error()})
+		return false
+	}
+	return true
+}
+
+// remote returns the IP of the client of the request, the one of the
+// connection unless it is a trusted proxy, in which case the forwarded
+// addresses are walked back to the first one not trusted.
+func (h *Handler) remote(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(strings.TrimSpace(host))
+	if ip == nil {
+		return ""
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	if address := r.Header.Get("X-Real-IP"); len(r.Header.Get("X-Forwarded-For")) == 0 && len(address) > 0 {
+		forwarded = []string{address}
+	}
+	for i := len(forwarded) - 1; i >= 0 && h.trusted(ip); i-- {
+		next := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if next == nil {
+			break
+		}
+		ip = next
+	}
+	return ip.String()
+}
+
+func (h *Handler) trusted(ip net.IP) bool {
+	for _, proxy := range h.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
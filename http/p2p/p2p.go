@@ -1,23 +1,56 @@
 package p2p
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/indexus/go-indexus-core/domain"
+	"github.com/indexus/go-indexus-core/security"
 
 	"github.com/rs/cors"
 )
 
 type Contact struct {
-	Name string         `json:"name"`
-	IPs  map[string]any `json:"ips"`
-	Port int            `json:"port"`
-	IP   string         `json:"ip"`
+	Name  string         `json:"name"`
+	IPs   map[string]any `json:"ips"`
+	Port  int            `json:"port"`
+	IP    string         `json:"ip"`
+	Load  domain.Load    `json:"load"`
+	TLS   bool           `json:"tls,omitempty"`
+	Proof *domain.Proof  `json:"proof,omitempty"`
+}
+
+// Redirect is an area handed to a peer, sent with the area containing it.
+type Redirect struct {
+	Key     domain.Key `json:"key"`
+	Contact Contact    `json:"contact"`
+}
+
+// redirects converts the redirects handed over with an area.
+func (h *Handler) redirects(redirects []Redirect) []domain.Redirect {
+	result := make([]domain.Redirect, 0, len(redirects))
+	for _, redirect := range redirects {
+		contact := h.NewContact(redirect.Contact.Name, redirect.Contact.IPs, redirect.Contact.Port)
+		result = append(result, domain.Redirect{Key: redirect.Key, Contact: contact})
+	}
+	return result
+}
+
+// proof returns the proof of work of the contact, if it carries one.
+func proof(contact domain.Contact) *domain.Proof {
+	if prover, ok := contact.(domain.Prover); ok {
+		return prover.Proof()
+	}
+	return nil
 }
 
 type Peer struct {
@@ -26,6 +59,9 @@ type Peer struct {
 }
 
 func NewPeer(name string) (*Peer, error) {
+	if err := domain.ValidateName("origin", name); err != nil {
+		return nil, err
+	}
 	id, err := domain.DecodeName(name)
 	if err != nil {
 		return nil, err
@@ -46,61 +82,134 @@ func (p *Peer) Name() string {
 }
 
 type Service interface {
-	Ping(domain.Contact) (domain.Contact, error)
-	Neighbors(domain.Peer) ([]domain.Contact, error)
-	Random(domain.Peer) (domain.Contact, error)
-	Transfer(domain.Peer, domain.Key, []*domain.Item) error
-	Get(string, string) (domain.Contact, *domain.Set, error)
-	New(*domain.Item, string, string) error
+	Ping(context.Context, domain.Contact) (domain.Contact, error)
+	Neighbors(context.Context, domain.Peer) ([]domain.Contact, error)
+	Random(context.Context, domain.Peer) (domain.Contact, error)
+	Transfer(context.Context, domain.Peer, domain.Key, domain.Policy, []*domain.Item, []domain.Witness, []domain.Redirect) error
+	Adopt(context.Context, domain.Peer, domain.Key, domain.Policy, []*domain.Item, []domain.Witness, []domain.Redirect) error
+	Get(context.Context, string, string, uint64) (domain.Contact, *domain.Set, error)
+	Changes(context.Context, string, string, uint64) (domain.Contact, *domain.Delta, error)
+	New(context.Context, *domain.Item, domain.Policy, string, string, []string) error
+	Insert(context.Context, *domain.Item, domain.Policy, string, string, []string) (domain.Contact, string, error)
+	Batch(context.Context, []*domain.Insertion) ([]error, error)
+	Subscribe(context.Context, string, string, []string) (<-chan domain.Event, error)
+	Watch(domain.Peer, domain.Key)
+	Invalidate(context.Context, domain.Peer, domain.Key, *domain.Set) error
+	Known(domain.Peer) bool
 }
 
 type Handler struct {
 	Service    Service
 	NewContact func(string, map[string]any, int) domain.Contact
+	Identity   *domain.Identity
+	secure     bool
+	mutual     bool
+	difficulty int
+	tokens     *domain.Tokens
+	limiters   [3]*domain.Limiter
+	proxies    []*net.IPNet
+	replays    *domain.Replays
 }
 
 // New - Create a HTTP handler
-func NewHttpHandler(service Service, newContact func(string, map[string]any, int) domain.Contact) *Handler {
+func NewHttpHandler(service Service, newContact func(string, map[string]any, int) domain.Contact, identity *domain.Identity) *Handler {
 	return &Handler{
 		Service:    service,
 		NewContact: newContact,
+		Identity:   identity,
+		replays:    domain.NewReplays(replays),
 	}
 }
 
+// Secure advertises TLS in Ping and requires it on the peer endpoints, with
+// the identity certificate of the peer when mutual.
+func (h *Handler) Secure(mutual bool) {
+	h.secure = true
+	h.mutual = mutual
+}
+
+// Restrict requires the proof of work of the difficulty from the signers of
+// the peer endpoints.
+func (h *Handler) Restrict(difficulty int) {
+	h.difficulty = difficulty
+}
+
 // Serve - Run the HTTP server
 func (h *Handler) Serve(lis net.Listener) error {
 
 	mux := http.NewServeMux()
 
 	// Discovery
-	mux.HandleFunc("/ping", h.Ping)
+	mux.HandleFunc("/ping", h.authenticate(maxBody, discovery, h.limit(maintenance, h.Ping)))
 
 	// Peer
-	mux.HandleFunc("/neighbors", h.Neighbors)
-	mux.HandleFunc("/random", h.Random)
-	mux.HandleFunc("/transfer", h.Transfer)
+	mux.HandleFunc("/neighbors", h.authenticate(maxBody, restricted, h.limit(maintenance, h.Neighbors)))
+	mux.HandleFunc("/random", h.authenticate(maxBody, restricted, h.limit(maintenance, h.Random)))
+	mux.HandleFunc("/transfer", h.authenticate(maxBatchBody, restricted, h.limit(maintenance, h.fresh(h.Transfer))))
+	mux.HandleFunc("/adopt", h.authenticate(maxBatchBody, restricted, h.limit(maintenance, h.fresh(h.Adopt))))
+	mux.HandleFunc("/invalidate", h.authenticate(maxBatchBody, restricted, h.limit(maintenance, h.fresh(h.Invalidate))))
 
 	// Client
-	mux.HandleFunc("/set", h.Get)
-	mux.HandleFunc("/item", h.New)
+	mux.HandleFunc("/set", h.authenticate(maxBody, public, h.limit(reads, h.Get)))
+	mux.HandleFunc("/item", h.authenticate(maxBody, public, h.limit(writes, h.fresh(h.New))))
+	// Batches are charged one write per item once decoded
+	mux.HandleFunc("/items", h.authenticate(maxBatchBody, public, h.fresh(h.Batch)))
+	mux.HandleFunc("/subscribe", h.verify(maxBody, public, h.limit(reads, h.Subscribe)))
 
 	// Configure CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Idempotency-Key", "Prefer", "If-None-Match"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowCredentials: false,
 	})
 
 	handler := c.Handler(mux)
 
-	s := &http.Server{Handler: handler}
+	s := &http.Server{
+		Handler: handler,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, stateKey{}, security.State(c))
+		},
+	}
 
 	log.Println("P2P HTTP Server started")
 
 	return s.Serve(lis)
 }
 
+// fail answers with the error: 400 with the invalid field when the request
+// fails validation, 403 when it is forbidden or comes from an unknown peer and
+// 503 otherwise, with a retry
+// hint when the queue is full.
+func fail(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrFull) {
+		w.Header().Set("Retry-After", "1")
+	}
+	code, body := status(err)
+	writeJSON(w, code, body)
+}
+
+// status returns the code and the body answering the error.
+func status(err error) (int, map[string]string) {
+	body := map[string]string{"error": err.Error(), "kind": domain.Kind(err)}
+
+	var invalid *domain.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		body["field"], body["reason"] = invalid.Field, invalid.Reason
+		return http.StatusBadRequest, body
+	case errors.Is(err, domain.ErrForbidden), errors.Is(err, domain.ErrUnauthorized):
+		return http.StatusForbidden, body
+	case errors.Is(err, domain.ErrFull):
+		body["retry"] = "1s"
+		return http.StatusServiceUnavailable, body
+	default:
+		return http.StatusServiceUnavailable, body
+	}
+}
+
 func writeJSON(w http.ResponseWriter, code int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -118,22 +227,29 @@ func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ips, err := getClientIPs(r)
-	if err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	if err := domain.ValidateName("origin", bodyReq.Origin.Name); err != nil {
+		fail(w, err)
+		return
+	}
+
+	if impersonates(w, r, bodyReq.Origin.Name) {
+		return
+	}
+
+	ip := h.remote(r)
+	if len(ip) == 0 {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "no valid IP found"})
 		return
 	}
 
 	if bodyReq.Origin.IPs == nil {
 		bodyReq.Origin.IPs = make(map[string]any)
 	}
-	for _, ip := range ips {
-		bodyReq.Origin.IPs[ip] = nil
-	}
+	bodyReq.Origin.IPs[ip] = nil
 
-	origin := h.NewContact(bodyReq.Origin.Name, bodyReq.Origin.IPs, bodyReq.Origin.Port)
+	origin := domain.Prove(h.NewContact(bodyReq.Origin.Name, bodyReq.Origin.IPs, bodyReq.Origin.Port), signer(r).Proof())
 
-	contact, err := h.Service.Ping(origin)
+	contact, err := h.Service.Ping(r.Context(), origin)
 	if err != nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
 		return
@@ -148,9 +264,12 @@ func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
 		Contact Contact `json:"contact"`
 	}{
 		Contact: Contact{
-			Name: contact.Name(),
-			IPs:  contact.IPs(),
-			Port: contact.Port(),
+			Name:  contact.Name(),
+			IPs:   contact.IPs(),
+			Port:  contact.Port(),
+			Load:  contact.Load(),
+			TLS:   h.secure,
+			Proof: proof(contact),
 		},
 	}
 	writeJSON(w, http.StatusOK, bodyResp)
@@ -161,13 +280,17 @@ func (h *Handler) Neighbors(w http.ResponseWriter, r *http.Request) {
 
 	origin, err := NewPeer(r.URL.Query().Get("origin"))
 	if err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		fail(w, err)
+		return
+	}
+
+	if impersonates(w, r, origin.Name()) {
 		return
 	}
 
-	contacts, err := h.Service.Neighbors(origin)
+	contacts, err := h.Service.Neighbors(r.Context(), origin)
 	if err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		fail(w, err)
 		return
 	}
 
@@ -178,10 +301,11 @@ func (h *Handler) Neighbors(w http.ResponseWriter, r *http.Request) {
 	}
 	for _, contact := range contacts {
 		body.Neighbors = append(body.Neighbors, Contact{
-			Name: contact.Name(),
-			IPs:  contact.IPs(),
-			Port: contact.Port(),
-			IP:   contact.IP(),
+			Name:  contact.Name(),
+			IPs:   contact.IPs(),
+			Port:  contact.Port(),
+			IP:    contact.IP(),
+			Proof: proof(contact),
 		})
 	}
 	writeJSON(w, http.StatusOK, body)
@@ -192,11 +316,15 @@ func (h *Handler) Random(w http.ResponseWriter, r *http.Request) {
 
 	origin, err := NewPeer(r.URL.Query().Get("origin"))
 	if err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		fail(w, err)
 		return
 	}
 
-	random, err := h.Service.Random(origin)
+	if impersonates(w, r, origin.Name()) {
+		return
+	}
+
+	random, err := h.Service.Random(r.Context(), origin)
 	if err != nil {
 		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
 		return
@@ -211,10 +339,11 @@ func (h *Handler) Random(w http.ResponseWriter, r *http.Request) {
 		Random Contact `json:"random"`
 	}{
 		Random: Contact{
-			Name: random.Name(),
-			IPs:  random.IPs(),
-			Port: random.Port(),
-			IP:   random.IP(),
+			Name:  random.Name(),
+			IPs:   random.IPs(),
+			Port:  random.Port(),
+			IP:    random.IP(),
+			Proof: proof(random),
 		},
 	}
 	writeJSON(w, http.StatusOK, body)
@@ -223,10 +352,76 @@ func (h *Handler) Random(w http.ResponseWriter, r *http.Request) {
 // Transfer handles the /transfer endpoint
 func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
 
+	var body struct {
+		Origin    string           `json:"origin"`
+		Key       domain.Key       `json:"key"`
+		Policy    domain.Policy    `json:"policy"`
+		Items     []*domain.Item   `json:"items"`
+		Keys      []domain.Witness `json:"keys"`
+		Redirects []Redirect       `json:"redirects"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+
+	origin, err := NewPeer(body.Origin)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	if impersonates(w, r, origin.Name()) {
+		return
+	}
+
+	if err := h.Service.Transfer(r.Context(), origin, body.Key, body.Policy, body.Items, body.Keys, h.redirects(body.Redirects)); err != nil {
+		fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Adopt handles the /adopt endpoint
+func (h *Handler) Adopt(w http.ResponseWriter, r *http.Request) {
+
+	var body struct {
+		Origin    string           `json:"origin"`
+		Key       domain.Key       `json:"key"`
+		Policy    domain.Policy    `json:"policy"`
+		Items     []*domain.Item   `json:"items"`
+		Keys      []domain.Witness `json:"keys"`
+		Redirects []Redirect       `json:"redirects"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+
+	origin, err := NewPeer(body.Origin)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	if impersonates(w, r, origin.Name()) {
+		return
+	}
+
+	if err := h.Service.Adopt(r.Context(), origin, body.Key, body.Policy, body.Items, body.Keys, h.redirects(body.Redirects)); err != nil {
+		fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Invalidate handles the /invalidate endpoint
+func (h *Handler) Invalidate(w http.ResponseWriter, r *http.Request) {
+
 	var body struct {
 		Origin string         `json:"origin"`
 		Key    domain.Key     `json:"key"`
-		Items  []*domain.Item `json:"items"`
+		Set    map[string]int `json:"set"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
@@ -235,15 +430,27 @@ func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
 
 	origin, err := NewPeer(body.Origin)
 	if err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		fail(w, err)
 		return
 	}
 
-	if err := h.Service.Transfer(origin, body.Key, body.Items); err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	if impersonates(w, r, origin.Name()) {
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
+
+	var set *domain.Set
+	if body.Set != nil {
+		set = domain.NewSet()
+		for key, value := range body.Set {
+			set.Put(key, value)
+		}
+	}
+
+	if err := h.Service.Invalidate(r.Context(), origin, body.Key, set); err != nil {
+		fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // Get handles the /set endpoint
@@ -251,99 +458,410 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	collection := r.URL.Query().Get("collection")
 	location := r.URL.Query().Get("location")
 
-	contact, set, err := h.Service.Get(collection, location)
+	if err := domain.ValidateKey(domain.Key{Collection: collection, Location: location}); err != nil {
+		fail(w, err)
+		return
+	}
+
+	if !h.permit(w, r, domain.Read, collection) {
+		return
+	}
+
+	version, err := since(r)
 	if err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		fail(w, err)
+		return
+	}
+
+	if value := r.URL.Query().Get("delta"); len(value) > 0 {
+		delta, err := strconv.ParseBool(value)
+		if err != nil {
+			fail(w, &domain.ValidationError{Field: "delta", Reason: fmt.Sprintf("%q is not a boolean", value)})
+			return
+		}
+		if delta {
+			h.changes(w, r, collection, location, version)
+			return
+		}
+	}
+
+	contact, set, err := h.Service.Get(r.Context(), collection, location, version)
+	unchanged := errors.Is(err, domain.ErrNotModified)
+	if err != nil && !unchanged {
+		fail(w, err)
+		return
+	}
+
+	if signature := signer(r); signature != nil && (set != nil || unchanged) {
+		if origin, err := NewPeer(signature.Name); err == nil {
+			h.Service.Watch(origin, domain.Key{Collection: collection, Location: location})
+		}
+	}
+
+	if unchanged {
+		w.Header().Set("ETag", etag(version))
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	var list map[string]int
 	if set != nil {
+		version = set.Version()
 		list = set.List()
+		w.Header().Set("ETag", etag(version))
 	}
 
 	var body = struct {
 		Contact Contact        `json:"contact"`
 		Set     map[string]int `json:"set"`
+		Version uint64         `json:"version,omitempty"`
 	}{
 		Contact: Contact{
-			Name: contact.Name(),
-			IPs:  contact.IPs(),
-			Port: contact.Port(),
-			IP:   contact.IP(),
+			Name:  contact.Name(),
+			IPs:   contact.IPs(),
+			Port:  contact.Port(),
+			IP:    contact.IP(),
+			Proof: proof(contact),
 		},
-		Set: list,
+		Set:     list,
+		Version: version,
 	}
 
 	writeJSON(w, http.StatusOK, body)
 }
 
+// changes answers the /set endpoint with the entries of the set changed since
+// the version, the whole set when the version is unknown.
+func (h *Handler) changes(w http.ResponseWriter, r *http.Request, collection, location string, version uint64) {
+	contact, delta, err := h.Service.Changes(r.Context(), collection, location, version)
+	unchanged := errors.Is(err, domain.ErrNotModified)
+	if err != nil && !unchanged {
+		fail(w, err)
+		return
+	}
+
+	if signature := signer(r); signature != nil && (delta != nil || unchanged) {
+		if origin, err := NewPeer(signature.Name); err == nil {
+			h.Service.Watch(origin, domain.Key{Collection: collection, Location: location})
+		}
+	}
+
+	if unchanged {
+		w.Header().Set("ETag", etag(version))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var body = struct {
+		Contact Contact `json:"contact"`
+		*domain.Delta
+	}{
+		Contact: Contact{
+			Name:  contact.Name(),
+			IPs:   contact.IPs(),
+			Port:  contact.Port(),
+			IP:    contact.IP(),
+			Proof: proof(contact),
+		},
+		Delta: delta,
+	}
+	if delta != nil {
+		w.Header().Set("ETag", etag(delta.Version))
+	}
+
+	writeJSON(w, http.StatusOK, body)
+}
+
+// since returns the version of the set known to the caller, from the version
+// parameter or the If-None-Match header, 0 for none.
+func since(r *http.Request) (uint64, error) {
+	if value := r.URL.Query().Get("version"); len(value) > 0 {
+		version, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return 0, &domain.ValidationError{Field: "version", Reason: fmt.Sprintf("%q is not a version", value)}
+		}
+		return version, nil
+	}
+
+	// Tags other than the versions of this node never match
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "W/"), `"`)
+		if version, err := strconv.ParseUint(tag, 10, 64); err == nil {
+			return version, nil
+		}
+	}
+	return 0, nil
+}
+
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
 // New handles the /item endpoint
 func (h *Handler) New(w http.ResponseWriter, r *http.Request) {
 	var body struct {
-		Item    *domain.Item `json:"item"`
-		Root    string       `json:"root"`
-		Current string       `json:"current"`
+		Item    *domain.Item  `json:"item"`
+		Policy  domain.Policy `json:"policy"`
+		Root    string        `json:"root"`
+		Current string        `json:"current"`
+		Hops    []string      `json:"hops"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if err := h.Service.New(body.Item, body.Root, body.Current); err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	if key := r.Header.Get("Idempotency-Key"); len(key) > 0 && body.Item != nil && len(body.Item.Idempotency) == 0 {
+		body.Item.Idempotency = key
+	}
+	if err := body.Item.Validate(); err != nil {
+		fail(w, err)
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
+	if !h.permit(w, r, domain.Write, body.Item.Collection) {
+		return
+	}
+
+	wait, timeout, err := waiting(r)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	if !wait {
+		if err := h.Service.New(r.Context(), body.Item, body.Policy, body.Root, body.Current, body.Hops); err != nil {
+			fail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	ctx := r.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	contact, location, err := h.Service.Insert(ctx, body.Item, body.Policy, body.Root, body.Current, body.Hops)
+	if errors.Is(err, domain.ErrPending) {
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "pending", "reason": err.Error()})
+		return
+	}
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"contact": Contact{
+			Name:  contact.Name(),
+			IPs:   contact.IPs(),
+			Port:  contact.Port(),
+			IP:    contact.IP(),
+			Proof: proof(contact),
+		},
+		"location": location,
+	})
 }
 
-// getClientIPs extracts all IPv4 and IPv6 addresses from the HTTP request.
-func getClientIPs(r *http.Request) ([]string, error) {
-	var ips []string
+// waiting reads whether the client waits for the insertion of the item, with
+// ?wait=true or ?wait=<duration>, or the Prefer: wait=<seconds> header. The
+// timeout is 0 when not given.
+func waiting(r *http.Request) (bool, time.Duration, error) {
+	if value := r.URL.Query().Get("wait"); len(value) > 0 {
+		if wait, err := strconv.ParseBool(value); err == nil {
+			return wait, 0, nil
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return false, 0, &domain.ValidationError{Field: "wait", Reason: fmt.Sprintf("%q is neither a boolean nor a positive duration", value)}
+		}
+		return true, timeout, nil
+	}
 
-	// Helper function to parse and append IPs from a comma-separated string.
-	parseAndAppendIPs := func(ipStr string) {
-		ipList := strings.Split(ipStr, ",")
-		for _, ip := range ipList {
-			ip = strings.TrimSpace(ip)
-			if ip == "" {
-				continue
-			}
-			parsedIP := net.ParseIP(ip)
-			if parsedIP != nil {
-				ips = append(ips, parsedIP.String())
-			}
+	for _, preference := range strings.Split(r.Header.Get("Prefer"), ",") {
+		value, found := strings.CutPrefix(strings.TrimSpace(preference), "wait=")
+		if !found {
+			continue
 		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return false, 0, &domain.ValidationError{Field: "wait", Reason: fmt.Sprintf("preference %q is not a positive number of seconds", value)}
+		}
+		return true, time.Duration(seconds) * time.Second, nil
 	}
+	return false, 0, nil
+}
 
-	// Check the X-Real-IP header.
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		parseAndAppendIPs(ip)
+// Batch handles the /items endpoint
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) {
+	insertions, err := decodeInsertions(r)
+	if errors.Is(err, errBatchSize) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if !h.charge(w, r, writes, len(insertions)) {
+		return
 	}
 
-	// Check the X-Forwarded-For header.
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parseAndAppendIPs(xff)
+	errs := make([]error, len(insertions))
+	valid := make([]*domain.Insertion, 0, len(insertions))
+	indexes := make([]int, 0, len(insertions))
+	for i, insertion := range insertions {
+		if err := insertion.Item.Validate(); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := h.scope(r, domain.Write, insertion.Item.Collection); err != nil {
+			if !errors.Is(err, domain.ErrForbidden) {
+				h.deny(w, err)
+				return
+			}
+			errs[i] = err
+			continue
+		}
+		valid = append(valid, insertion)
+		indexes = append(indexes, i)
 	}
 
-	// Fall back to r.RemoteAddr.
-	remoteIP := r.RemoteAddr
-	if remoteIP != "" {
-		// Attempt to split the host and port.
-		host, _, err := net.SplitHostPort(remoteIP)
+	if len(valid) > 0 {
+		results, err := h.Service.Batch(r.Context(), valid)
 		if err != nil {
-			// If splitting fails, use the entire RemoteAddr.
-			host = remoteIP
+			fail(w, err)
+			return
 		}
-		host = strings.TrimSpace(host)
-		parsedIP := net.ParseIP(host)
-		if parsedIP != nil {
-			ips = append(ips, parsedIP.String())
+		for j, i := range indexes {
+			if j < len(results) {
+				errs[i] = results[j]
+			}
+		}
+	}
+
+	type result struct {
+		Status int    `json:"status"`
+		Error  string `json:"error,omitempty"`
+		Kind   string `json:"kind,omitempty"`
+		Field  string `json:"field,omitempty"`
+		Reason string `json:"reason,omitempty"`
+		Retry  string `json:"retry,omitempty"`
+	}
+	body := struct {
+		Accepted int      `json:"accepted"`
+		Rejected int      `json:"rejected"`
+		Results  []result `json:"results"`
+	}{Results: make([]result, len(errs))}
+	for i, err := range errs {
+		if err == nil {
+			body.Accepted++
+			body.Results[i] = result{Status: http.StatusCreated}
+			continue
 		}
+		body.Rejected++
+		code, answer := status(err)
+		body.Results[i] = result{Status: code, Error: answer["error"], Kind: answer["kind"], Field: answer["field"], Reason: answer["reason"], Retry: answer["retry"]}
+	}
+
+	writeJSON(w, http.StatusOK, body)
+}
+
+// heartbeat is the interval of the comments keeping a subscription open.
+const heartbeat = 15 * time.Second
+
+// Subscribe handles the /subscribe endpoint
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	collection, location := r.URL.Query().Get("collection"), r.URL.Query().Get("location")
+	if err := domain.ValidateKey(domain.Key{Collection: collection, Location: location}); err != nil {
+		fail(w, err)
+		return
+	}
+	if !h.permit(w, r, domain.Read, collection) {
+		return
 	}
 
-	if len(ips) == 0 {
-		return nil, errors.New("no valid IPs found")
+	// Only peers relay subscriptions
+	var hops []string
+	if value := r.URL.Query().Get("hops"); len(value) > 0 && h.peer(r) {
+		hops = strings.Split(value, ",")
 	}
 
-	return ips, nil
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+
+	events, err := h.Service.Subscribe(r.Context(), collection, location, hops)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		case <-ticker.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// maxBatch is the maximum number of items of a request to /items.
+const maxBatch = 10_000
+
+var errBatchSize = fmt.Errorf("more than %d items", maxBatch)
+
+// decodeInsertions reads the insertions of a batch, sent as a JSON array or
+// as one JSON object per line with the application/x-ndjson content type.
+func decodeInsertions(r *http.Request) ([]*domain.Insertion, error) {
+	var insertions []*domain.Insertion
+	decoder := json.NewDecoder(r.Body)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") {
+		for {
+			insertion := &domain.Insertion{}
+			if err := decoder.Decode(insertion); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if len(insertions) == maxBatch {
+				return nil, errBatchSize
+			}
+			insertions = append(insertions, insertion)
+		}
+	} else if err := decoder.Decode(&insertions); err != nil {
+		return nil, err
+	}
+
+	if len(insertions) > maxBatch {
+		return nil, errBatchSize
+	}
+	for i, insertion := range insertions {
+		if insertion == nil {
+			insertions[i] = &domain.Insertion{}
+		}
+	}
+	return insertions, nil
 }
@@ -0,0 +1,163 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/indexus/go-indexus-core/domain"
+	"github.com/indexus/go-indexus-core/security"
+)
+
+type signerKey struct{}
+
+type stateKey struct{}
+
+// access is the level of authentication required by an endpoint.
+type access int
+
+const (
+	// public endpoints verify the signature of the request if any
+	public access = iota
+	// discovery endpoints require a signature, also over plain connections
+	discovery
+	// restricted endpoints require a signature, and TLS when the handler is
+	// secure, with the identity certificate of the peer when it is mutual
+	restricted
+)
+
+// signedWriter buffers the response to sign it once complete.
+type signedWriter struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (w *signedWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+func (w *signedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+const (
+	// maxBody is the size of the body of a request at most
+	maxBody = 1 << 20
+	// maxBatchBody is the one of the requests carrying many items, the
+	// batches and the areas handed over, as long as the binary frames
+	maxBatchBody = 64 << 20
+)
+
+// authenticate verifies the signature of the request, mandatory on the peer
+// endpoints and optional on the client ones, and signs the response. Bodies
+// longer than size are refused.
+func (h *Handler) authenticate(size int64, level access, next http.HandlerFunc) http.HandlerFunc {
+	return h.verify(size, level, func(w http.ResponseWriter, r *http.Request) {
+		var request []byte
+		if signature := signer(r); signature != nil {
+			request = signature.Value
+		}
+
+		sw := &signedWriter{ResponseWriter: w, code: http.StatusOK}
+		next(sw, r)
+
+		if h.Identity != nil {
+			signature := h.Identity.Seal(domain.ResponseMessage(request, sw.body.Bytes()))
+			w.Header().Set(domain.SignatureHeader, signature.String())
+		}
+		w.WriteHeader(sw.code)
+		w.Write(sw.body.Bytes())
+	})
+}
+
+// verify verifies the signature of the request like authenticate, without
+// signing the response, which is streamed.
+func (h *Handler) verify(size int64, level access, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		state, _ := r.Context().Value(stateKey{}).(*tls.ConnectionState)
+		if level == restricted && h.secure && state == nil {
+			writeJSON(w, http.StatusUpgradeRequired, map[string]string{"error": "TLS required"})
+			return
+		}
+		if level == restricted && h.mutual && len(security.Peer(state)) == 0 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "identity certificate required"})
+			return
+		}
+
+		var tooLarge *http.MaxBytesError
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, size))
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if header := r.Header.Get(domain.SignatureHeader); len(header) > 0 {
+			signature, err := domain.ParseSignature(header)
+			if err == nil {
+				err = signature.Verify(domain.RequestMessage(r.Method, r.RequestURI, body))
+			}
+			if err == nil {
+				err = security.Tie(state, signature.Name)
+			}
+			if err != nil {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+				return
+			}
+			if level != public && signature.Proof().Work() < h.difficulty {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": domain.ErrProof.Error()})
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), signerKey{}, signature))
+		} else if level != public {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "signature required"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// replays is the number of signatures of the requests changing the node
+// remembered to refuse their replay.
+const replays = 100_000
+
+// fresh refuses the signed requests already received, for the endpoints
+// changing the node. Two identical reads signed in the same second have the
+// same signature, and are harmless to repeat.
+func (h *Handler) fresh(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if signature := signer(r); signature != nil {
+			if err := h.replays.Check(signature); err != nil {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// signer returns the signature of the request, nil if it is not signed.
+func signer(r *http.Request) *domain.Signature {
+	signature, _ := r.Context().Value(signerKey{}).(*domain.Signature)
+	return signature
+}
+
+// impersonates reports whether the origin claimed in the request is not the
+// node which signed it, and answers with an error if so.
+func impersonates(w http.ResponseWriter, r *http.Request, origin string) bool {
+	if signature := signer(r); signature == nil || origin != signature.Name {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "origin does not match the signature"})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package p2p
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyRefusesBodiesBeyondTheSize(t *testing.T) {
+	h := NewHttpHandler(&registry{}, nil, nil)
+	called := false
+	handler := h.verify(16, public, func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/item", bytes.NewReader(make([]byte, 17))))
+	if w.Code != http.StatusRequestEntityTooLarge || called {
+		t.Errorf("body beyond the size answered %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/item", bytes.NewReader(make([]byte, 16))))
+	if w.Code != http.StatusOK || !called {
+		t.Errorf("body within the size answered %d", w.Code)
+	}
+}
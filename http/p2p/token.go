@@ -0,0 +1,67 @@
+package p2p
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// Authorize requires a bearer token granting the scope of the request on the
+// client endpoints, the known peers being recognized by their signature
+// instead.
+func (h *Handler) Authorize(tokens *domain.Tokens) {
+	h.tokens = tokens
+}
+
+// peer reports whether the request is signed by a peer registered by the
+// node. Without difficulty anyone may register, so that no signer is a peer.
+func (h *Handler) peer(r *http.Request) bool {
+	signature := signer(r)
+	if signature == nil || h.difficulty <= 0 || signature.Proof().Work() < h.difficulty {
+		return false
+	}
+	origin, err := NewPeer(signature.Name)
+	return err == nil && h.Service.Known(origin)
+}
+
+// permit checks that the request may run the action on the collection, and
+// answers with an error if not.
+func (h *Handler) permit(w http.ResponseWriter, r *http.Request, action, collection string) bool {
+	err := h.scope(r, action, collection)
+	if err != nil {
+		h.deny(w, err)
+	}
+	return err == nil
+}
+
+var errBearer = errors.New("bearer token required")
+
+// scope checks that the request may run the action on the collection.
+func (h *Handler) scope(r *http.Request, action, collection string) error {
+	if h.tokens == nil || h.peer(r) {
+		return nil
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return errBearer
+	}
+	return h.tokens.Allow(token, action, collection)
+}
+
+// deny answers with the error of the token of the request.
+func (h *Handler) deny(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errBearer):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, domain.ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case err != nil:
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+}
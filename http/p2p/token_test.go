@@ -0,0 +1,94 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// registry is a service knowing the peers of the test only.
+type registry struct {
+	Service
+	known map[string]bool
+}
+
+func (r *registry) Known(peer domain.Peer) bool {
+	return r.known[peer.Name()]
+}
+
+func identity(t *testing.T, difficulty int) *domain.Identity {
+	t.Helper()
+	identity, err := domain.NewIdentity(difficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+// signed returns a request verified as signed by the identity, if any, with
+// the bearer token, if any.
+func signed(signer *domain.Identity, token string) *http.Request {
+	r := httptest.NewRequest("GET", "/set", nil)
+	if len(token) > 0 {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if signer != nil {
+		r = r.WithContext(context.WithValue(r.Context(), signerKey{}, signer.Seal(nil)))
+	}
+	return r
+}
+
+func TestOnlyRegisteredPeersProvingWorkAreExemptFromTokens(t *testing.T) {
+	const difficulty = 4
+	known, unknown, weak := identity(t, difficulty), identity(t, difficulty), identity(t, 0)
+	for weak.Proof().Work() >= difficulty {
+		weak = identity(t, 0)
+	}
+
+	tokens, err := domain.NewTokens([]domain.Grant{{Name: "client", Token: "secret", Scopes: []string{"read:*"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHttpHandler(&registry{known: map[string]bool{known.Name(): true, weak.Name(): true}}, nil, nil)
+	h.Restrict(difficulty)
+	h.Authorize(tokens)
+
+	if err := h.scope(signed(known, ""), domain.Read, "c"); err != nil {
+		t.Errorf("registered peer refused: %v", err)
+	}
+	if err := h.scope(signed(unknown, ""), domain.Read, "c"); !errors.Is(err, errBearer) {
+		t.Errorf("unknown signer answered %v, want %v", err, errBearer)
+	}
+	if err := h.scope(signed(weak, ""), domain.Read, "c"); !errors.Is(err, errBearer) {
+		t.Errorf("registered peer lacking the work answered %v, want %v", err, errBearer)
+	}
+
+	// Clients and unknown signers need a token granting the scope
+	if err := h.scope(signed(unknown, "secret"), domain.Read, "c"); err != nil {
+		t.Errorf("granted token refused: %v", err)
+	}
+	if err := h.scope(signed(nil, "secret"), domain.Write, "c"); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("token lacking the scope answered %v, want %v", err, domain.ErrForbidden)
+	}
+	if err := h.scope(signed(nil, "other"), domain.Read, "c"); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("unknown token answered %v, want %v", err, domain.ErrUnauthorized)
+	}
+}
+
+func TestWithoutDifficultyNoSignerIsExempt(t *testing.T) {
+	known := identity(t, 0)
+	tokens, err := domain.NewTokens([]domain.Grant{{Name: "client", Token: "secret", Scopes: []string{"*"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHttpHandler(&registry{known: map[string]bool{known.Name(): true}}, nil, nil)
+	h.Authorize(tokens)
+
+	if err := h.scope(signed(known, ""), domain.Read, "c"); !errors.Is(err, errBearer) {
+		t.Errorf("registered peer without difficulty answered %v, want %v", err, errBearer)
+	}
+}
@@ -0,0 +1,699 @@
+package peer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+	"github.com/indexus/go-indexus-core/security"
+	"github.com/indexus/go-indexus-core/wire"
+)
+
+// errPlain is returned by a handshake over a plain connection to a peer
+// requiring TLS, the handshake is then repeated over TLS.
+var errPlain = errors.New("peer requires TLS")
+
+// PoolSize is the maximum number of connections opened to a single peer,
+// and so the number of calls in flight to it.
+var PoolSize = 4
+
+type connection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// version is the version negotiated by the handshake of the connection
+	version int
+}
+
+func (c *connection) close() {
+	c.conn.Close()
+}
+
+// BinaryContact speaks the binary protocol over a pool of persistent
+// connections and falls back to the HTTP transport of Contact when the peer
+// does not.
+type BinaryContact struct {
+	*Contact
+	mu           *sync.Mutex
+	slots        chan struct{}
+	idle         chan *connection
+	version      int
+	capabilities wire.Capabilities
+	fallback     bool
+}
+
+func NewBinaryContact(name string, ips map[string]any, port int) domain.Contact {
+	return newBinaryContact(&Contact{
+		name: name,
+		ips:  ips,
+		port: port,
+	})
+}
+
+func newBinaryContact(contact *Contact) *BinaryContact {
+	return &BinaryContact{
+		Contact: contact,
+		mu:      &sync.Mutex{},
+		slots:   make(chan struct{}, PoolSize),
+		idle:    make(chan *connection, PoolSize),
+	}
+}
+
+func (b *BinaryContact) secured() bool {
+	return b.Contact.secure.Load() && TLSConfig != nil
+}
+
+func fromWire(contact wire.Contact) *BinaryContact {
+	c := &Contact{
+		name:  contact.Name,
+		ips:   contact.IPs,
+		ip:    contact.IP,
+		port:  contact.Port,
+		proof: contact.Proof,
+	}
+	c.update(contact.Load, false)
+	return newBinaryContact(c)
+}
+
+// wrap converts the contacts returned by the HTTP transport.
+func wrap(contact domain.Contact) domain.Contact {
+	if c, ok := contact.(*Contact); ok && c != nil {
+		return newBinaryContact(c)
+	}
+	return contact
+}
+
+func (b *BinaryContact) IP() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.ip
+}
+
+// Version returns the version negotiated with the peer, 0 over HTTP.
+func (b *BinaryContact) Version() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.version
+}
+
+// acquire reserves one of the connections of the pool, it returns an idle
+// connection if there is one.
+func (b *BinaryContact) acquire(ctx context.Context) (*connection, error) {
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case c := <-b.idle:
+		return c, nil
+	default:
+		return nil, nil
+	}
+}
+
+// release frees the reservation, keeping the connection for later calls.
+func (b *BinaryContact) release(c *connection) {
+	if c != nil {
+		b.idle <- c
+	}
+	<-b.slots
+}
+
+func (b *BinaryContact) Ping(ctx context.Context, origin domain.Contact) (domain.Contact, error) {
+	contact, err := b.ping(ctx, origin)
+	if errors.Is(err, wire.ErrProtocol) {
+		contact, err := b.Contact.Ping(ctx, origin)
+		return wrap(contact), err
+	}
+	return contact, err
+}
+
+func (b *BinaryContact) ping(ctx context.Context, origin domain.Contact) (domain.Contact, error) {
+	if b.unsupported() {
+		return nil, wire.ErrProtocol
+	}
+
+	c, err := b.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil {
+		if contact, err := b.hello(ctx, c, origin); err == nil {
+			b.release(c)
+			return contact, nil
+		}
+		c.close()
+	}
+
+	for ip := range b.ips {
+		c, contact, err := b.greet(ctx, ip, origin)
+		if errors.Is(err, wire.ErrProtocol) {
+			b.release(nil)
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+
+		b.mu.Lock()
+		b.ip = ip
+		b.mu.Unlock()
+
+		b.release(c)
+		return contact, nil
+	}
+
+	b.release(nil)
+	return nil, fmt.Errorf("no hosts found from ips and port provided")
+}
+
+// greet opens a connection to the ip and sends the handshake, again over TLS
+// if the peer requires it.
+func (b *BinaryContact) greet(ctx context.Context, ip string, origin domain.Contact) (*connection, domain.Contact, error) {
+	c, err := b.dial(ctx, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contact, err := b.hello(ctx, c, origin)
+	if errors.Is(err, errPlain) {
+		c.close()
+		if c, err = b.dial(ctx, ip); err != nil {
+			return nil, nil, err
+		}
+		contact, err = b.hello(ctx, c, origin)
+	}
+	if err != nil {
+		c.close()
+		return nil, nil, err
+	}
+	return c, contact, nil
+}
+
+func (b *BinaryContact) unsupported() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.fallback
+}
+
+// dial opens a connection to the ip and exchanges the preface.
+func (b *BinaryContact) dial(ctx context.Context, ip string) (*connection, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(b.port)))
+	if err != nil {
+		return nil, err
+	}
+
+	if b.secured() {
+		secure := tls.Client(conn, security.Dial(TLSConfig, expected(b.name)))
+		if err := secure.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = secure
+	}
+
+	stop := watch(ctx, conn)
+	defer stop()
+
+	if _, err := conn.Write([]byte(wire.Preface)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	if err := wire.ReadPreface(reader); err != nil {
+		conn.Close()
+		if errors.Is(err, wire.ErrProtocol) {
+			b.mu.Lock()
+			b.fallback = true
+			b.mu.Unlock()
+			return nil, err
+		}
+		return nil, fmt.Errorf("error reading preface: %v", err)
+	}
+
+	return &connection{conn: conn, reader: reader}, nil
+}
+
+// watch bounds the operations on the connection by the deadline and the
+// cancellation of the context, until stop is called.
+func watch(ctx context.Context, conn net.Conn) func() {
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	return func() {
+		stop()
+		conn.SetDeadline(time.Time{})
+	}
+}
+
+// hello sends the handshake over the connection.
+func (b *BinaryContact) hello(ctx context.Context, c *connection, origin domain.Contact) (domain.Contact, error) {
+	if origin == nil {
+		origin = &Contact{}
+	}
+
+	e := wire.NewEncoder()
+	e.Int(wire.Version)
+	e.Uint(uint64(wire.Supported))
+	e.Contact(origin)
+
+	d, signer, err := exchange(ctx, c, wire.Hello, e.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	version, capabilities, remote := d.Int(), wire.Capabilities(d.Uint()), d.Contact()
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+
+	// Peers older than the oldest version spoken are reached over HTTP
+	if version < wire.MinVersion || version > wire.Version {
+		b.mu.Lock()
+		b.fallback = true
+		b.mu.Unlock()
+		return nil, fmt.Errorf("%w: version %d", wire.ErrProtocol, version)
+	}
+	c.version = version
+
+	// The peer proves it owns the key of the name it answers with
+	if remote.Name != signer {
+		return nil, fmt.Errorf("%w: handshake signed by %s instead of %s", domain.ErrSignature, signer, remote.Name)
+	}
+
+	ip, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+	secure := capabilities.Has(wire.Secure)
+
+	b.mu.Lock()
+	b.version, b.capabilities = version, capabilities
+	b.mu.Unlock()
+	b.update(remote.Load, secure)
+
+	// The connection is reopened over TLS for the next calls
+	if secure && TLSConfig != nil && security.State(c.conn) == nil {
+		return nil, errPlain
+	}
+
+	contact := fromWire(remote)
+	contact.ips[ip] = nil
+	contact.ip = ip
+	contact.secure.Store(secure)
+
+	return contact, nil
+}
+
+// exchange sends a signed request and verifies the signature of the response,
+// the signer being returned. The response is decoded for the version of the
+// connection.
+func exchange(ctx context.Context, c *connection, t wire.Type, payload []byte) (*wire.Decoder, string, error) {
+	stop := watch(ctx, c.conn)
+	defer stop()
+
+	frame, request := wire.Seal(Identity, t, payload, nil)
+	if err := wire.WriteFrame(c.conn, t, frame); err != nil {
+		return nil, "", err
+	}
+
+	// Only the handshake is answered before the version is known
+	limit := uint32(wire.MaxFrame)
+	if c.version == 0 {
+		limit = wire.MaxHandshake
+	}
+	t, frame, err := wire.ReadFrame(c.reader, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	signature, response, err := wire.Open(t, frame, request)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := security.Tie(security.State(c.conn), signature.Name); err != nil {
+		return nil, "", err
+	}
+
+	if t == wire.Error {
+		return nil, signature.Name, refusal(response)
+	}
+	if c.version == 0 {
+		return wire.NewDecoder(response), signature.Name, nil
+	}
+	return wire.NewDecoderAt(response, c.version), signature.Name, nil
+}
+
+// refusal rebuilds the error of an Error frame, with its kind when the peer
+// sends it.
+func refusal(response []byte) error {
+	d := wire.NewDecoder(response)
+	message, kind := d.String(), d.String()
+	if d.Err() != nil || len(kind) == 0 {
+		kind = "error"
+	}
+	return domain.KindError(kind, fmt.Sprintf("error from peer: %s", message))
+}
+
+// call sends a request over a connection of the pool, opening it first if
+// needed, the request being encoded for the version of the connection. It
+// returns wire.ErrProtocol when the peer only speaks HTTP.
+func (b *BinaryContact) call(ctx context.Context, t wire.Type, encode func(*wire.Encoder)) (*wire.Decoder, error) {
+	if b.unsupported() {
+		return nil, wire.ErrProtocol
+	}
+
+	c, err := b.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if c == nil {
+		b.mu.Lock()
+		ip := b.ip
+		b.mu.Unlock()
+
+		if ip == "" {
+			b.release(nil)
+			return nil, fmt.Errorf("no ip known for %s", b.name)
+		}
+
+		c, err = b.dial(ctx, ip)
+		if err != nil {
+			b.release(nil)
+			return nil, err
+		}
+		if _, err := b.hello(ctx, c, nil); err != nil {
+			c.close()
+			b.release(nil)
+			return nil, err
+		}
+	}
+
+	e := wire.NewEncoderAt(c.version)
+	encode(e)
+
+	// The connection stays in the pool when the peer answers with an error
+	d, signer, err := exchange(ctx, c, t, e.Bytes())
+	if err != nil && !domain.Answered(err) {
+		c.close()
+		b.release(nil)
+		return nil, err
+	}
+
+	b.release(c)
+	if signer != b.name {
+		return nil, fmt.Errorf("%w: response signed by %s instead of %s", domain.ErrSignature, signer, b.name)
+	}
+	return d, err
+}
+
+func (b *BinaryContact) Neighbors(ctx context.Context, origin domain.Peer) ([]domain.Contact, error) {
+	d, err := b.call(ctx, wire.Neighbors, func(e *wire.Encoder) {
+		e.String(origin.Name())
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		contacts, err := b.Contact.Neighbors(ctx, origin)
+		for i := range contacts {
+			contacts[i] = wrap(contacts[i])
+		}
+		return contacts, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors := d.Contacts()
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.Contact, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		contact := fromWire(neighbor)
+		if contact.name == b.name {
+			contact.ip = b.IP()
+		}
+		result = append(result, contact)
+	}
+	return result, nil
+}
+
+func (b *BinaryContact) Random(ctx context.Context, origin domain.Peer) (domain.Contact, error) {
+	d, err := b.call(ctx, wire.Random, func(e *wire.Encoder) {
+		e.String(origin.Name())
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		contact, err := b.Contact.Random(ctx, origin)
+		return wrap(contact), err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !d.Bool() {
+		return nil, d.Err()
+	}
+	random := d.Contact()
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	return fromWire(random), nil
+}
+
+func (b *BinaryContact) Transfer(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	_, err := b.call(ctx, wire.Transfer, func(e *wire.Encoder) {
+		e.String(origin.Name())
+		e.Key(key)
+		e.Policy(policy)
+		e.Items(items)
+		e.Witnesses(witnesses)
+		e.Redirects(redirects)
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		return b.Contact.Transfer(ctx, origin, key, policy, items, witnesses, redirects)
+	}
+	return err
+}
+
+func (b *BinaryContact) Adopt(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	b.mu.Lock()
+	supported := b.fallback || b.version == 0 || b.capabilities.Has(wire.Adoption)
+	b.mu.Unlock()
+
+	if !supported {
+		return fmt.Errorf("peer %s does not support adoption", b.name)
+	}
+
+	_, err := b.call(ctx, wire.Adopt, func(e *wire.Encoder) {
+		e.String(origin.Name())
+		e.Key(key)
+		e.Policy(policy)
+		e.Items(items)
+		e.Witnesses(witnesses)
+		e.Redirects(redirects)
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		return b.Contact.Adopt(ctx, origin, key, policy, items, witnesses, redirects)
+	}
+	return err
+}
+
+func (b *BinaryContact) Get(ctx context.Context, collection string, location string, version uint64) (domain.Contact, *domain.Set, error) {
+	d, err := b.call(ctx, wire.Get, func(e *wire.Encoder) {
+		e.String(collection)
+		e.String(location)
+		e.Since(version)
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		contact, set, err := b.Contact.Get(ctx, collection, location, version)
+		return wrap(contact), set, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unchanged, contact := d.Unchanged(), d.Contact()
+	if unchanged != nil {
+		if err := d.Err(); err != nil {
+			return nil, nil, err
+		}
+		return fromWire(contact), nil, unchanged
+	}
+	set := d.Set()
+	if err := d.Err(); err != nil {
+		return nil, nil, err
+	}
+	return fromWire(contact), set, nil
+}
+
+func (b *BinaryContact) Changes(ctx context.Context, collection string, location string, version uint64) (domain.Contact, *domain.Delta, error) {
+	b.mu.Lock()
+	supported := b.fallback || b.version == 0 || b.capabilities.Has(wire.Deltas)
+	b.mu.Unlock()
+
+	// Peers without deltas send the whole set
+	if !supported {
+		contact, set, err := b.Get(ctx, collection, location, version)
+		if err != nil || set == nil {
+			return contact, nil, err
+		}
+		return contact, set.Since(0), nil
+	}
+
+	d, err := b.call(ctx, wire.Changes, func(e *wire.Encoder) {
+		e.String(collection)
+		e.String(location)
+		e.Uint(version)
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		contact, delta, err := b.Contact.Changes(ctx, collection, location, version)
+		return wrap(contact), delta, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unchanged, contact := d.Error(), d.Contact()
+	if unchanged != nil {
+		if err := d.Err(); err != nil {
+			return nil, nil, err
+		}
+		return fromWire(contact), nil, unchanged
+	}
+	var delta *domain.Delta
+	if d.Bool() {
+		delta = d.Delta()
+	}
+	if err := d.Err(); err != nil {
+		return nil, nil, err
+	}
+	return fromWire(contact), delta, nil
+}
+
+func (b *BinaryContact) Invalidate(ctx context.Context, origin domain.Peer, key domain.Key, set *domain.Set) error {
+	b.mu.Lock()
+	supported := b.fallback || b.version == 0 || b.capabilities.Has(wire.Invalidation)
+	b.mu.Unlock()
+
+	// Peers without invalidation poll the sets they cache
+	if !supported {
+		return fmt.Errorf("peer %s does not support invalidation", b.name)
+	}
+
+	_, err := b.call(ctx, wire.Invalidate, func(e *wire.Encoder) {
+		e.String(origin.Name())
+		e.Key(key)
+		e.Set(set)
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		return b.Contact.Invalidate(ctx, origin, key, set)
+	}
+	return err
+}
+
+func (b *BinaryContact) New(ctx context.Context, item *domain.Item, policy domain.Policy, root string, current string, hops []string) error {
+	_, err := b.call(ctx, wire.New, func(e *wire.Encoder) {
+		e.Item(item)
+		e.Policy(policy)
+		e.String(root)
+		e.String(current)
+		e.Hops(hops)
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		return b.Contact.New(ctx, item, policy, root, current, hops)
+	}
+	return err
+}
+
+func (b *BinaryContact) Insert(ctx context.Context, item *domain.Item, policy domain.Policy, root string, current string, hops []string) (domain.Contact, string, error) {
+	b.mu.Lock()
+	supported := b.fallback || b.version == 0 || b.capabilities.Has(wire.Acknowledgment)
+	b.mu.Unlock()
+
+	// Peers without acknowledgment only queue the item
+	if !supported {
+		if err := b.New(ctx, item, policy, root, current, hops); err != nil {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("%w: peer %s does not acknowledge insertions", domain.ErrPending, b.name)
+	}
+
+	wait := time.Duration(0)
+	if deadline, ok := ctx.Deadline(); ok {
+		wait = time.Until(deadline)
+	}
+
+	d, err := b.call(ctx, wire.Insert, func(e *wire.Encoder) {
+		e.Item(item)
+		e.Policy(policy)
+		e.String(root)
+		e.String(current)
+		e.Hops(hops)
+		e.Int(int(wait.Milliseconds()))
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		contact, location, err := b.Contact.Insert(ctx, item, policy, root, current, hops)
+		return wrap(contact), location, err
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := d.Error(); err != nil {
+		return nil, "", err
+	}
+	contact, location := d.Contact(), d.String()
+	if err := d.Err(); err != nil {
+		return nil, "", err
+	}
+	return fromWire(contact), location, nil
+}
+
+func (b *BinaryContact) Batch(ctx context.Context, insertions []*domain.Insertion) ([]error, error) {
+	b.mu.Lock()
+	supported := b.fallback || b.version == 0 || b.capabilities.Has(wire.Batching)
+	b.mu.Unlock()
+
+	// Peers without batching get the items one by one
+	if !supported {
+		results := make([]error, len(insertions))
+		for i, insertion := range insertions {
+			results[i] = b.New(ctx, insertion.Item, insertion.Policy, insertion.Root, insertion.Current, insertion.Hops)
+		}
+		return results, nil
+	}
+
+	d, err := b.call(ctx, wire.Batch, func(e *wire.Encoder) {
+		e.Insertions(insertions)
+	})
+	if errors.Is(err, wire.ErrProtocol) {
+		return b.Contact.Batch(ctx, insertions)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := d.Errors()
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) != len(insertions) {
+		return nil, fmt.Errorf("%d results for %d items", len(results), len(insertions))
+	}
+	return results, nil
+}
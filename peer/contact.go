@@ -1,50 +1,80 @@
 package peer
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/indexus/go-indexus-core/domain"
+	"github.com/indexus/go-indexus-core/security"
 )
 
+var dialer = &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+
+// HttpClient keeps a pool of idle connections per peer, the deadlines are
+// given by the context of every call.
 var HttpClient = &http.Client{
-	Timeout: 200 * time.Millisecond,
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        1024,
+		MaxIdleConnsPerHost: 8,
+		IdleConnTimeout:     90 * time.Second,
+	},
 }
 
 type Contact struct {
-	name string
-	ips  map[string]any
-	ip   string
-	port int
+	name  string
+	ips   map[string]any
+	ip    string
+	port  int
+	proof *domain.Proof
+	// The figures of the last Ping, updated while the contact is in use
+	load   atomic.Pointer[domain.Load]
+	secure atomic.Bool
 }
 
 // Implementing json.Marshaler interface
 func (c *Contact) MarshalJSON() ([]byte, error) {
 	type Alias struct {
-		Name string         `json:"name"`
-		IPs  map[string]any `json:"ips"`
-		IP   string         `json:"ip"`
-		Port int            `json:"port"`
+		Name  string         `json:"name"`
+		IPs   map[string]any `json:"ips"`
+		IP    string         `json:"ip"`
+		Port  int            `json:"port"`
+		Load  domain.Load    `json:"load"`
+		TLS   bool           `json:"tls,omitempty"`
+		Proof *domain.Proof  `json:"proof,omitempty"`
 	}
 	return json.Marshal(&Alias{
-		Name: c.name,
-		IPs:  c.ips,
-		IP:   c.ip,
-		Port: c.port,
+		Name:  c.name,
+		IPs:   c.ips,
+		IP:    c.ip,
+		Port:  c.port,
+		Load:  c.Load(),
+		TLS:   c.secure.Load(),
+		Proof: c.proof,
 	})
 }
 
 // Implementing json.Unmarshaler interface
 func (c *Contact) UnmarshalJSON(data []byte) error {
 	type Alias struct {
-		Name string         `json:"name"`
-		IPs  map[string]any `json:"ips"`
-		IP   string         `json:"ip"`
-		Port int            `json:"port"`
+		Name  string         `json:"name"`
+		IPs   map[string]any `json:"ips"`
+		IP    string         `json:"ip"`
+		Port  int            `json:"port"`
+		Load  domain.Load    `json:"load"`
+		TLS   bool           `json:"tls,omitempty"`
+		Proof *domain.Proof  `json:"proof,omitempty"`
 	}
 	aux := &Alias{}
 	if err := json.Unmarshal(data, &aux); err != nil {
@@ -54,6 +84,8 @@ func (c *Contact) UnmarshalJSON(data []byte) error {
 	c.ips = aux.IPs
 	c.ip = aux.IP
 	c.port = aux.Port
+	c.proof = aux.Proof
+	c.update(aux.Load, aux.TLS)
 	return nil
 }
 
@@ -93,9 +125,36 @@ func (c *Contact) Host() string {
 	return fmt.Sprintf("%s@%s|%d", c.name, c.ip, c.port)
 }
 
-func (c *Contact) Ping(origin domain.Contact) (domain.Contact, error) {
+// Proof returns the proof of work of the id of the peer, nil if unknown.
+func (c *Contact) Proof() *domain.Proof {
+	return c.proof
+}
+
+// scheme is https once the peer advertised TLS in Ping.
+func (c *Contact) scheme() string {
+	if c.secure.Load() && TLSConfig != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Load returns the load figures received with the last Ping.
+func (c *Contact) Load() domain.Load {
+	if load := c.load.Load(); load != nil {
+		return *load
+	}
+	return domain.Load{}
+}
+
+// update records the figures received with a Ping.
+func (c *Contact) update(load domain.Load, secure bool) {
+	c.load.Store(&load)
+	c.secure.Store(secure)
+}
+
+func (c *Contact) Ping(ctx context.Context, origin domain.Contact) (domain.Contact, error) {
 	for ip := range c.ips {
-		contact, err := c.ping(origin, ip)
+		contact, err := c.ping(ctx, origin, ip)
 		if err != nil {
 			continue
 		}
@@ -104,22 +163,24 @@ func (c *Contact) Ping(origin domain.Contact) (domain.Contact, error) {
 	return nil, fmt.Errorf("no hosts found from ips and port provided")
 }
 
-func (c *Contact) ping(origin domain.Contact, ip string) (domain.Contact, error) {
+func (c *Contact) ping(ctx context.Context, origin domain.Contact, ip string) (domain.Contact, error) {
 	parsedIP := net.ParseIP(c.ip)
 
 	if parsedIP != nil && parsedIP.To4() == nil {
 		ip = fmt.Sprintf("[%s]", ip)
 	}
 
-	url := fmt.Sprintf("http://%s:%d/ping", ip, c.port)
+	url := fmt.Sprintf("%s://%s:%d/ping", c.scheme(), ip, c.port)
+	sender := &Contact{
+		name: origin.Name(),
+		ips:  origin.IPs(),
+		port: origin.Port(),
+	}
+	sender.update(origin.Load(), false)
 	reqBody := struct {
 		Origin *Contact `json:"origin"`
 	}{
-		Origin: &Contact{
-			name: origin.Name(),
-			ips:  origin.IPs(),
-			port: origin.Port(),
-		},
+		Origin: sender,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -127,66 +188,72 @@ func (c *Contact) ping(origin domain.Contact, ip string) (domain.Contact, error)
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := HttpClient.Do(req)
+	resp, err := c.send(req, jsonData)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, err
+	if resp.status != http.StatusOK {
+		return nil, resp.failure()
 	}
 
 	var respBody struct {
 		Contact *Contact `json:"contact"`
 	}
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&respBody); err != nil {
+	if err := json.Unmarshal(resp.body, &respBody); err != nil {
 		return nil, err
 	}
 
+	// The peer proves it owns the key of the name it answers with
 	contact := respBody.Contact
+	if err := resp.from(contact.name); err != nil {
+		return nil, err
+	}
+
 	contact.ips[ip] = nil
 	contact.ip = ip
 
+	c.update(contact.Load(), contact.secure.Load())
+
 	return contact, nil
 }
 
-func (c *Contact) Neighbors(origin domain.Peer) ([]domain.Contact, error) {
+func (c *Contact) Neighbors(ctx context.Context, origin domain.Peer) ([]domain.Contact, error) {
 	ip, parsedIP := c.ip, net.ParseIP(c.ip)
 
 	if parsedIP != nil && parsedIP.To4() == nil {
 		ip = fmt.Sprintf("[%s]", ip)
 	}
 
-	url := fmt.Sprintf("http://%s:%d/neighbors?origin=%s", ip, c.port, origin.Name())
+	url := fmt.Sprintf("%s://%s:%d/neighbors?origin=%s", c.scheme(), ip, c.port, origin.Name())
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %s", err.Error())
 	}
 
-	resp, err := HttpClient.Do(req)
+	resp, err := c.send(req, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %s", err.Error())
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error code: %d", resp.StatusCode)
+	if resp.status != http.StatusOK {
+		return nil, resp.failure()
+	}
+	if err := resp.from(c.name); err != nil {
+		return nil, err
 	}
 
 	var body struct {
 		Neighbors []*Contact `json:"neighbors"`
 	}
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&body); err != nil {
+	if err := json.Unmarshal(resp.body, &body); err != nil {
 		return nil, err
 	}
 
@@ -199,57 +266,173 @@ func (c *Contact) Neighbors(origin domain.Peer) ([]domain.Contact, error) {
 	return domain.ConvertToContactSlice(body.Neighbors), nil
 }
 
-func (c *Contact) Random(origin domain.Peer) (domain.Contact, error) {
+func (c *Contact) Random(ctx context.Context, origin domain.Peer) (domain.Contact, error) {
 	ip, parsedIP := c.ip, net.ParseIP(c.ip)
 
 	if parsedIP != nil && parsedIP.To4() == nil {
 		ip = fmt.Sprintf("[%s]", ip)
 	}
 
-	url := fmt.Sprintf("http://%s:%d/random?origin=%s", ip, c.port, origin.Name())
+	url := fmt.Sprintf("%s://%s:%d/random?origin=%s", c.scheme(), ip, c.port, origin.Name())
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %s", err.Error())
 	}
 
-	resp, err := HttpClient.Do(req)
+	resp, err := c.send(req, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %s", err.Error())
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error code: %d", resp.StatusCode)
+	if resp.status != http.StatusOK {
+		return nil, resp.failure()
+	}
+	if err := resp.from(c.name); err != nil {
+		return nil, err
 	}
 
 	var body struct {
 		Contact *Contact `json:"contact"`
 	}
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&body); err != nil {
+	if err := json.Unmarshal(resp.body, &body); err != nil {
 		return nil, err
 	}
 
 	return body.Contact, nil
 }
 
-func (c *Contact) Transfer(origin domain.Peer, key domain.Key, items []*domain.Item) error {
+func (c *Contact) Transfer(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	return c.move(ctx, "transfer", origin, key, policy, items, witnesses, redirects)
+}
+
+func (c *Contact) Adopt(ctx context.Context, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	return c.move(ctx, "adopt", origin, key, policy, items, witnesses, redirects)
+}
+
+// move sends the items of an area, with their idempotency keys and the
+// redirects of the areas below it, to the /transfer or /adopt endpoint.
+func (c *Contact) move(ctx context.Context, endpoint string, origin domain.Peer, key domain.Key, policy domain.Policy, items []*domain.Item, witnesses []domain.Witness, redirects []domain.Redirect) error {
+	ip, parsedIP := c.ip, net.ParseIP(c.ip)
+
+	if parsedIP != nil && parsedIP.To4() == nil {
+		ip = fmt.Sprintf("[%s]", ip)
+	}
+
+	type redirect struct {
+		Key     domain.Key `json:"key"`
+		Contact *Contact   `json:"contact"`
+	}
+
+	url := fmt.Sprintf("%s://%s:%d/%s", c.scheme(), ip, c.port, endpoint)
+	body := struct {
+		Origin    string           `json:"origin"`
+		Key       domain.Key       `json:"key"`
+		Policy    domain.Policy    `json:"policy"`
+		Items     []*domain.Item   `json:"items"`
+		Keys      []domain.Witness `json:"keys,omitempty"`
+		Redirects []redirect       `json:"redirects,omitempty"`
+	}{
+		Origin: origin.Name(),
+		Key:    key,
+		Policy: policy,
+		Items:  items,
+		Keys:   witnesses,
+	}
+	for _, r := range redirects {
+		contact := &Contact{name: r.Contact.Name(), ips: r.Contact.IPs(), ip: r.Contact.IP(), port: r.Contact.Port()}
+		body.Redirects = append(body.Redirects, redirect{Key: r.Key, Contact: contact})
+	}
+
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.send(req, jsonData)
+	if err != nil {
+		return err
+	}
+
+	if resp.status != http.StatusCreated {
+		return resp.failure()
+	}
+
+	return resp.from(c.name)
+}
+
+// Changes fetches the delta of a set since the version from the /set
+// endpoint.
+func (c *Contact) Changes(ctx context.Context, collection string, location string, version uint64) (domain.Contact, *domain.Delta, error) {
+	ip, parsedIP := c.ip, net.ParseIP(c.ip)
+
+	if parsedIP != nil && parsedIP.To4() == nil {
+		ip = fmt.Sprintf("[%s]", ip)
+	}
+
+	url := fmt.Sprintf("%s://%s:%d/set?collection=%s&location=%s&delta=true", c.scheme(), ip, c.port, collection, location)
+	if version > 0 {
+		url += fmt.Sprintf("&version=%d", version)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.send(req, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.status != http.StatusOK && resp.status != http.StatusNotModified {
+		return nil, nil, resp.failure()
+	}
+	if err := resp.from(c.name); err != nil {
+		return nil, nil, err
+	}
+	if resp.status == http.StatusNotModified {
+		return nil, nil, domain.ErrNotModified
+	}
+
+	var body struct {
+		Contact *Contact `json:"contact"`
+		domain.Delta
+	}
+	if err := json.Unmarshal(resp.body, &body); err != nil {
+		return nil, nil, err
+	}
+	if body.Changes == nil {
+		return body.Contact, nil, nil
+	}
+	return body.Contact, &body.Delta, nil
+}
+
+// Invalidate pushes the set of an area owned by the origin to the /invalidate
+// endpoint, nil when it is no longer owned.
+func (c *Contact) Invalidate(ctx context.Context, origin domain.Peer, key domain.Key, set *domain.Set) error {
 	ip, parsedIP := c.ip, net.ParseIP(c.ip)
 
 	if parsedIP != nil && parsedIP.To4() == nil {
 		ip = fmt.Sprintf("[%s]", ip)
 	}
 
-	url := fmt.Sprintf("http://%s:%d/transfer", ip, c.port)
+	url := fmt.Sprintf("%s://%s:%d/invalidate", c.scheme(), ip, c.port)
 	body := struct {
 		Origin string         `json:"origin"`
 		Key    domain.Key     `json:"key"`
-		Items  []*domain.Item `json:"items"`
+		Set    map[string]int `json:"set"`
 	}{
 		Origin: origin.Name(),
 		Key:    key,
-		Items:  items,
+	}
+	if set != nil {
+		body.Set = set.List()
 	}
 
 	jsonData, err := json.Marshal(body)
@@ -257,49 +440,61 @@ func (c *Contact) Transfer(origin domain.Peer, key domain.Key, items []*domain.I
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := HttpClient.Do(req)
+	resp, err := c.send(req, jsonData)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("error code: %d", resp.StatusCode)
+	if resp.status != http.StatusNoContent {
+		return resp.failure()
 	}
 
-	return nil
+	return resp.from(c.name)
 }
 
-func (c *Contact) Get(collection string, location string) (domain.Contact, *domain.Set, error) {
+func (c *Contact) Get(ctx context.Context, collection string, location string, version uint64) (domain.Contact, *domain.Set, error) {
 	ip, parsedIP := c.ip, net.ParseIP(c.ip)
 
 	if parsedIP != nil && parsedIP.To4() == nil {
 		ip = fmt.Sprintf("[%s]", ip)
 	}
 
-	url := fmt.Sprintf("http://%s:%d/set?collection=%s&location=%s", ip, c.port, collection, location)
-	resp, err := HttpClient.Get(url)
+	url := fmt.Sprintf("%s://%s:%d/set?collection=%s&location=%s", c.scheme(), ip, c.port, collection, location)
+	if version > 0 {
+		url += fmt.Sprintf("&version=%d", version)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, nil, fmt.Errorf("error code: %d", resp.StatusCode)
+	resp, err := c.send(req, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.status != http.StatusOK && resp.status != http.StatusNotModified {
+		return nil, nil, resp.failure()
+	}
+	if err := resp.from(c.name); err != nil {
+		return nil, nil, err
+	}
+	if resp.status == http.StatusNotModified {
+		return nil, nil, domain.ErrNotModified
 	}
 
 	var body struct {
 		Contact *Contact       `json:"contact"`
 		Set     map[string]int `json:"set"`
+		Version uint64         `json:"version"`
 	}
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&body); err != nil {
+	if err := json.Unmarshal(resp.body, &body); err != nil {
 		return nil, nil, err
 	}
 
@@ -307,26 +502,31 @@ func (c *Contact) Get(collection string, location string) (domain.Contact, *doma
 	for key, value := range body.Set {
 		set.Put(key, value)
 	}
+	set.SetVersion(body.Version)
 
 	return body.Contact, set, nil
 }
 
-func (c *Contact) New(item *domain.Item, root string, current string) error {
+func (c *Contact) New(ctx context.Context, item *domain.Item, policy domain.Policy, root string, current string, hops []string) error {
 	ip, parsedIP := c.ip, net.ParseIP(c.ip)
 
 	if parsedIP != nil && parsedIP.To4() == nil {
 		ip = fmt.Sprintf("[%s]", ip)
 	}
 
-	url := fmt.Sprintf("http://%s:%d/item", ip, c.port)
+	url := fmt.Sprintf("%s://%s:%d/item", c.scheme(), ip, c.port)
 	body := struct {
-		Item    *domain.Item `json:"item"`
-		Root    string       `json:"root"`
-		Current string       `json:"current"`
+		Item    *domain.Item  `json:"item"`
+		Policy  domain.Policy `json:"policy"`
+		Root    string        `json:"root"`
+		Current string        `json:"current"`
+		Hops    []string      `json:"hops,omitempty"`
 	}{
 		Item:    item,
+		Policy:  policy,
 		Root:    root,
 		Current: current,
+		Hops:    hops,
 	}
 
 	jsonData, err := json.Marshal(body)
@@ -334,21 +534,201 @@ func (c *Contact) New(item *domain.Item, root string, current string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := HttpClient.Do(req)
+	resp, err := c.send(req, jsonData)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("error code: %d", resp.StatusCode)
+	if resp.status != http.StatusCreated {
+		return resp.failure()
 	}
 
-	return nil
+	return resp.from(c.name)
+}
+
+func (c *Contact) Batch(ctx context.Context, insertions []*domain.Insertion) ([]error, error) {
+	ip, parsedIP := c.ip, net.ParseIP(c.ip)
+
+	if parsedIP != nil && parsedIP.To4() == nil {
+		ip = fmt.Sprintf("[%s]", ip)
+	}
+
+	url := fmt.Sprintf("%s://%s:%d/items", c.scheme(), ip, c.port)
+
+	jsonData, err := json.Marshal(insertions)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.send(req, jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.status != http.StatusOK {
+		return nil, resp.failure()
+	}
+	if err := resp.from(c.name); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Results []struct {
+			Error string `json:"error"`
+			Kind  string `json:"kind"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(resp.body, &body); err != nil {
+		return nil, err
+	}
+	if len(body.Results) != len(insertions) {
+		return nil, fmt.Errorf("%d results for %d items", len(body.Results), len(insertions))
+	}
+
+	results := make([]error, len(body.Results))
+	for i, result := range body.Results {
+		results[i] = domain.KindError(result.Kind, result.Error)
+	}
+	return results, nil
+}
+
+func (c *Contact) Insert(ctx context.Context, item *domain.Item, policy domain.Policy, root string, current string, hops []string) (domain.Contact, string, error) {
+	ip, parsedIP := c.ip, net.ParseIP(c.ip)
+
+	if parsedIP != nil && parsedIP.To4() == nil {
+		ip = fmt.Sprintf("[%s]", ip)
+	}
+
+	wait := "true"
+	if deadline, ok := ctx.Deadline(); ok {
+		wait = time.Until(deadline).Round(time.Millisecond).String()
+	}
+
+	url := fmt.Sprintf("%s://%s:%d/item?wait=%s", c.scheme(), ip, c.port, wait)
+	body := struct {
+		Item    *domain.Item  `json:"item"`
+		Policy  domain.Policy `json:"policy"`
+		Root    string        `json:"root"`
+		Current string        `json:"current"`
+		Hops    []string      `json:"hops,omitempty"`
+	}{
+		Item:    item,
+		Policy:  policy,
+		Root:    root,
+		Current: current,
+		Hops:    hops,
+	}
+
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.send(req, jsonData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch resp.status {
+	case http.StatusCreated:
+	case http.StatusAccepted:
+		return nil, "", fmt.Errorf("%w on %s", domain.ErrPending, c.name)
+	default:
+		return nil, "", resp.failure()
+	}
+	if err := resp.from(c.name); err != nil {
+		return nil, "", err
+	}
+
+	var receipt struct {
+		Contact  *Contact `json:"contact"`
+		Location string   `json:"location"`
+	}
+	if err := json.Unmarshal(resp.body, &receipt); err != nil {
+		return nil, "", err
+	}
+	if receipt.Contact == nil {
+		return nil, "", fmt.Errorf("no contact in the receipt of %s", c.name)
+	}
+	return receipt.Contact, receipt.Location, nil
+}
+
+// Subscribe streams the events sent by the peer, the stream being bound to
+// the connection rather than signed.
+func (c *Contact) Subscribe(ctx context.Context, collection string, location string, hops []string) (<-chan domain.Event, error) {
+	ip, parsedIP := c.ip, net.ParseIP(c.ip)
+
+	if parsedIP != nil && parsedIP.To4() == nil {
+		ip = fmt.Sprintf("[%s]", ip)
+	}
+
+	query := url.Values{"collection": {collection}, "location": {location}}
+	if len(hops) > 0 {
+		query.Set("hops", strings.Join(hops, ","))
+	}
+	url := fmt.Sprintf("%s://%s:%d/subscribe?%s", c.scheme(), ip, c.port, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	sign(req, nil)
+
+	resp, err := HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, (&response{status: resp.StatusCode, body: body}).failure()
+	}
+	if err := security.Tie(resp.TLS, c.name); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	events := make(chan domain.Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event domain.Event
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
 }
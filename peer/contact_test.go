@@ -0,0 +1,44 @@
+package peer
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/indexus/go-indexus-core/domain"
+	"github.com/indexus/go-indexus-core/wire"
+)
+
+func TestFailureKeepsTheKindAnsweredOverHttp(t *testing.T) {
+	cases := []struct {
+		response *response
+		target   error
+	}{
+		{&response{status: http.StatusForbidden, body: []byte(`{"error":"not allowed","kind":"forbidden"}`)}, domain.ErrForbidden},
+		{&response{status: http.StatusServiceUnavailable, body: []byte(`{"error":"queue full","kind":"full"}`)}, domain.ErrFull},
+		{&response{status: http.StatusTooManyRequests, body: []byte(`{"error":"rate limited"}`)}, domain.ErrRateLimited},
+		{&response{status: http.StatusUnauthorized, body: nil}, domain.ErrUnauthorized},
+	}
+	for _, c := range cases {
+		err := c.response.failure()
+		if !errors.Is(err, c.target) || !domain.Answered(err) {
+			t.Errorf("status %d answered %v, want %v", c.response.status, err, c.target)
+		}
+	}
+}
+
+func TestRefusalKeepsTheKindOfTheErrorFrame(t *testing.T) {
+	e := wire.NewEncoder()
+	e.String("item refused")
+	e.String(domain.Kind(domain.ErrInvalid))
+	if err := refusal(e.Bytes()); !errors.Is(err, domain.ErrInvalid) || !domain.Answered(err) {
+		t.Errorf("refusal answered %v, want %v", err, domain.ErrInvalid)
+	}
+
+	// Older peers send the message only
+	e = wire.NewEncoder()
+	e.String("failed")
+	if err := refusal(e.Bytes()); err == nil || !domain.Answered(err) {
+		t.Errorf("refusal of an older peer answered %v", err)
+	}
+}
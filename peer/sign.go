@@ -0,0 +1,148 @@
+package peer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/indexus/go-indexus-core/domain"
+	"github.com/indexus/go-indexus-core/security"
+)
+
+// Identity signs the messages sent to the peers, it is set at startup.
+var Identity *domain.Identity
+
+// TLSConfig reaches the peers advertising TLS, it is set by EnableTLS.
+var TLSConfig *tls.Config
+
+// EnableTLS sets the configuration used to call the peers over TLS, every
+// connection being verified against the name of the peer it is opened to.
+func EnableTLS(config *tls.Config) {
+	TLSConfig = config
+
+	transport := HttpClient.Transport.(*http.Transport)
+	transport.TLSClientConfig = config
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		secure := tls.Client(conn, security.Dial(config, security.Expected(ctx)))
+		if err := secure.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return secure, nil
+	}
+}
+
+// expected returns the name the certificate of the peer must carry, none for
+// the bootstrap peers whose name is unknown until they answer Ping.
+func expected(name string) string {
+	id, err := domain.DecodeName(name)
+	if err != nil || bytes.Equal(id, make([]byte, domain.IdLength())) {
+		return ""
+	}
+	return name
+}
+
+type response struct {
+	status int
+	body   []byte
+	signer string
+}
+
+// send signs the request with the identity of the node and verifies the
+// signature of successful responses, the signer being returned. A connection
+// opened over TLS must present the certificate of the peer.
+func (c *Contact) send(req *http.Request, body []byte) (*response, error) {
+	req = req.WithContext(security.Expect(req.Context(), expected(c.name)))
+	request := sign(req, body)
+
+	resp, err := HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &response{status: resp.StatusCode, body: data}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, nil
+	}
+
+	signature, err := domain.ParseSignature(resp.Header.Get(domain.SignatureHeader))
+	if err != nil {
+		return nil, err
+	}
+	if err := signature.Verify(domain.ResponseMessage(request, data)); err != nil {
+		return nil, err
+	}
+	if err := security.Tie(resp.TLS, signature.Name); err != nil {
+		return nil, err
+	}
+
+	result.signer = signature.Name
+	return result, nil
+}
+
+// sign signs the request with the identity of the node, returning the
+// signature the response is bound to.
+func sign(req *http.Request, body []byte) []byte {
+	if Identity == nil {
+		return nil
+	}
+	signature := Identity.Seal(domain.RequestMessage(req.Method, req.URL.RequestURI(), body))
+	req.Header.Set(domain.SignatureHeader, signature.String())
+	return signature.Value
+}
+
+// failure rebuilds the error answered by the peer, from its kind or else from
+// the status of the response, so that the callers tell a refusal from a
+// failure to reach the peer.
+func (r *response) failure() error {
+	var body struct {
+		Error string `json:"error"`
+		Kind  string `json:"kind"`
+	}
+	if json.Unmarshal(r.body, &body) != nil || len(body.Kind) == 0 {
+		body.Kind = kind(r.status)
+	}
+	if len(body.Error) == 0 {
+		body.Error = fmt.Sprintf("error code: %d", r.status)
+	}
+	return domain.KindError(body.Kind, body.Error)
+}
+
+// kind returns the kind of the error answered with the status.
+func kind(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusTooManyRequests:
+		return "limited"
+	default:
+		return "error"
+	}
+}
+
+// from checks that the response was signed by the peer.
+func (r *response) from(name string) error {
+	if r.signer != name {
+		return fmt.Errorf("%w: response signed by %s instead of %s", domain.ErrSignature, r.signer, name)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package security
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// Certificate creates a self-signed certificate for the key of the identity,
+// peers tie it to the name of the node.
+func Certificate(identity *domain.Identity) (tls.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: identity.Name(), SerialNumber: strconv.FormatUint(identity.Nonce(), 10)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, identity.Public(), identity.Private())
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error creating identity certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: identity.Private()}, nil
+}
+
+// Name returns the name of the node of an identity certificate, derived
+// from its key and the nonce held in the serial number of its subject.
+func Name(cert *x509.Certificate) (string, bool) {
+	public, ok := cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return "", false
+	}
+	nonce, err := strconv.ParseUint(cert.Subject.SerialNumber, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	proof := &domain.Proof{Key: public, Nonce: nonce}
+	name := domain.EncodeId(proof.ID())
+	return name, cert.Subject.CommonName == name
+}
+
+// Pool returns the system certificate authorities and those of the file.
+func Pool(filename string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if len(filename) == 0 {
+		return pool, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate authorities: %v", err)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in %s", filename)
+	}
+	return pool, nil
+}
+
+// verify accepts the identity certificates, whose name is checked against the
+// signature of the messages, and the certificates issued by the pool.
+func verify(pool *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(raw [][]byte, _ [][]*x509.Certificate) error {
+		if len(raw) == 0 {
+			return nil
+		}
+
+		certs := make([]*x509.Certificate, 0, len(raw))
+		for _, der := range raw {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+		return check(pool, certs, "")
+	}
+}
+
+// check accepts the identity certificate of the node of the name, or a
+// certificate of the pool issued to the name. Any identity certificate is
+// accepted without name.
+func check(pool *x509.CertPool, certs []*x509.Certificate, name string) error {
+	if node, ok := Name(certs[0]); ok {
+		if len(name) > 0 && node != name {
+			return fmt.Errorf("%w: %s instead of %s", ErrMismatch, node, name)
+		}
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		DNSName:       name,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// pin verifies the certificate of the peer against the node name the
+// connection was opened for, given as server name by Dial.
+func pin(pool *x509.CertPool) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("no certificate presented")
+		}
+
+		name := state.ServerName
+		if domain.ValidateName("server", name) != nil {
+			name = ""
+		}
+		return check(pool, state.PeerCertificates, name)
+	}
+}
+
+// ServerConfig serves the certificate and requests the identity certificate
+// of the peers, required by the peer endpoints with mutual TLS.
+func ServerConfig(certificate tls.Certificate, pool *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates:          []tls.Certificate{certificate},
+		ClientAuth:            tls.RequestClientCert,
+		VerifyPeerCertificate: verify(pool),
+		MinVersion:            tls.VersionTLS12,
+	}
+}
+
+// ClientConfig presents the identity certificate of the node to its peers,
+// which are reached by IP and so verified against the node name given by
+// Dial instead of their host name.
+func ClientConfig(certificate tls.Certificate, pool *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates:       []tls.Certificate{certificate},
+		InsecureSkipVerify: true,
+		VerifyConnection:   pin(pool),
+		MinVersion:         tls.VersionTLS12,
+	}
+}
+
+// Dial returns the client configuration for the node of the name, the
+// handshake failing unless the peer presents the identity certificate of the
+// name. The peers whose name is unknown, empty, may present any.
+func Dial(config *tls.Config, name string) *tls.Config {
+	config = config.Clone()
+	config.ServerName = name
+	return config
+}
+
+type expectedKey struct{}
+
+// Expect tells the dialer of the connections of the context the name of the
+// node they are opened to.
+func Expect(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, expectedKey{}, name)
+}
+
+// Expected returns the name given by Expect, empty if none.
+func Expected(ctx context.Context) string {
+	name, _ := ctx.Value(expectedKey{}).(string)
+	return name
+}
+
+// State returns the TLS state of a connection, nil if it is not encrypted.
+func State(conn net.Conn) *tls.ConnectionState {
+	for conn != nil {
+		if c, ok := conn.(*tls.Conn); ok {
+			state := c.ConnectionState()
+			return &state
+		}
+		wrapper, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		conn = wrapper.NetConn()
+	}
+	return nil
+}
+
+// Peer returns the name of the node of the identity certificate presented
+// over the connection, empty if none.
+func Peer(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	name, _ := Name(state.PeerCertificates[0])
+	return name
+}
+
+var ErrMismatch = errors.New("certificate does not match the signature")
+
+// Tie checks that the identity certificate presented over the connection, if
+// any, belongs to the node which signed the message.
+func Tie(state *tls.ConnectionState, signer string) error {
+	if name := Peer(state); len(name) > 0 && name != signer {
+		return fmt.Errorf("%w: %s instead of %s", ErrMismatch, name, signer)
+	}
+	return nil
+}
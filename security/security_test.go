@@ -0,0 +1,59 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// handshake connects a client for the name to a server presenting the
+// identity certificate of the identity.
+func handshake(t *testing.T, server *domain.Identity, name string) error {
+	t.Helper()
+
+	serverCertificate, err := Certificate(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := domain.NewIdentity(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCertificate, err := Certificate(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	left, right := net.Pipe()
+	defer left.Close()
+	defer right.Close()
+
+	go tls.Server(right, ServerConfig(serverCertificate, pool)).Handshake()
+	return tls.Client(left, Dial(ClientConfig(clientCertificate, pool), name)).Handshake()
+}
+
+func TestDialPinsTheName(t *testing.T) {
+	server, err := domain.NewIdentity(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := domain.NewIdentity(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handshake(t, server, server.Name()); err != nil {
+		t.Errorf("handshake with the expected node failed: %v", err)
+	}
+	if err := handshake(t, server, ""); err != nil {
+		t.Errorf("handshake with a node of unknown name failed: %v", err)
+	}
+	if err := handshake(t, server, other.Name()); !errors.Is(err, ErrMismatch) {
+		t.Errorf("handshake with another node answered %v, want %v", err, ErrMismatch)
+	}
+}
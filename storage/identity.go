@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// LoadIdentity reads the keypair of the node from the file, generating and
+// saving it on first start. When the proof of work is below the difficulty,
+// the nonce is mined again and saved if mine is true, as it changes the name
+// of the node, and the identity is refused otherwise.
+func LoadIdentity(filename string, difficulty int, mine bool) (*domain.Identity, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		identity, err := domain.NewIdentity(difficulty)
+		if err != nil {
+			return nil, err
+		}
+		return identity, saveIdentity(filename, identity)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading identity file: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("error decoding identity file: no private key found")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing identity key: %v", err)
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("error parsing identity key: not an Ed25519 key")
+	}
+
+	var nonce uint64
+	if value, exist := block.Headers["Nonce"]; exist {
+		if nonce, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("error parsing identity nonce: %v", err)
+		}
+	}
+
+	identity, err := domain.IdentityFromKey(private, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if work := identity.Proof().Work(); work < difficulty && !mine {
+		return nil, fmt.Errorf("%w: work %d of %s below difficulty %d, mine a new nonce with -mine, which changes the name of the node", domain.ErrProof, work, identity.Name(), difficulty)
+	}
+	if identity.Harden(difficulty) {
+		return identity, saveIdentity(filename, identity)
+	}
+	return identity, nil
+}
+
+func saveIdentity(filename string, identity *domain.Identity) error {
+	der, err := x509.MarshalPKCS8PrivateKey(identity.Private())
+	if err != nil {
+		return fmt.Errorf("error encoding identity key: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return fmt.Errorf("error creating identity directory: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{"Nonce": strconv.FormatUint(identity.Nonce(), 10)},
+		Bytes:   der,
+	})
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error writing identity file: %v", err)
+	}
+	return nil
+}
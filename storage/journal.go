@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Journal keeps the elements of the ingestion queue in a file, a line
+// "+seq data" being appended when an element is queued and "-seq" once it
+// is processed. The file is truncated whenever nothing is pending, and
+// compacted down to the pending elements once most of its lines are stale.
+type Journal struct {
+	mu       *sync.Mutex
+	filename string
+	file     *os.File
+	writer   *bufio.Writer
+	pending  int
+	lines    int
+}
+
+// compaction is the number of lines from which the journal is compacted
+// when they are more than twice the pending elements.
+const compaction = 1024
+
+// record is an element pending in the journal.
+type record struct {
+	seq  uint64
+	data []byte
+}
+
+func OpenJournal(filename string) (*Journal, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the journal file: %v", err)
+	}
+	return &Journal{
+		mu:       &sync.Mutex{},
+		filename: filename,
+		file:     file,
+		writer:   bufio.NewWriter(file),
+	}, nil
+}
+
+// Replay returns the data of the elements not processed, in the order they
+// were queued, which stay journaled under the sequence numbers 1 to n until
+// they are done.
+func (j *Journal) Replay() ([][]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]byte, 0, len(entries))
+	for i := range entries {
+		entries[i].seq = uint64(i + 1)
+		result = append(result, entries[i].data)
+	}
+	return result, j.rewrite(entries)
+}
+
+func (j *Journal) Append(seq uint64, data []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.pending++
+	j.lines++
+	return j.write(fmt.Sprintf("+%d %s\n", seq, data))
+}
+
+func (j *Journal) Done(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.pending--
+	if j.pending <= 0 {
+		j.pending, j.lines = 0, 0
+		return j.file.Truncate(0)
+	}
+	if err := j.write(fmt.Sprintf("-%d\n", seq)); err != nil {
+		return err
+	}
+	j.lines++
+
+	// The elements kept pending, such as the dead letters, would otherwise
+	// keep every line after them
+	if j.lines >= compaction && j.lines > 2*j.pending {
+		entries, err := j.scan()
+		if err != nil {
+			return err
+		}
+		return j.rewrite(entries)
+	}
+	return nil
+}
+
+// scan reads the elements pending in the file, in the order they were queued.
+func (j *Journal) scan() ([]record, error) {
+	if _, err := j.file.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	order := make([]uint64, 0)
+	entries := make(map[uint64][]byte)
+
+	scanner := bufio.NewScanner(j.file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) < 2 {
+			continue
+		}
+		head, data, _ := bytes.Cut(line[1:], []byte(" "))
+		seq, err := strconv.ParseUint(string(head), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch line[0] {
+		case '+':
+			order = append(order, seq)
+			entries[seq] = bytes.Clone(data)
+		case '-':
+			delete(entries, seq)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the journal file: %v", err)
+	}
+
+	result := make([]record, 0, len(entries))
+	for _, seq := range order {
+		if data, exist := entries[seq]; exist {
+			result = append(result, record{seq: seq, data: data})
+		}
+	}
+	return result, nil
+}
+
+// rewrite replaces the file with the entries. The replacement is written and
+// synced aside, then renamed over the file, so that a crash leaves either.
+func (j *Journal) rewrite(entries []record) error {
+	temp := j.filename + ".tmp"
+	file, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating the journal file: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	for _, record := range entries {
+		fmt.Fprintf(writer, "+%d %s\n", record.seq, record.data)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write the journal: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync the journal: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, j.filename); err != nil {
+		return fmt.Errorf("failed to replace the journal: %v", err)
+	}
+
+	j.file.Close()
+	j.file, err = os.OpenFile(j.filename, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening the journal file: %v", err)
+	}
+	j.writer = bufio.NewWriter(j.file)
+	j.pending, j.lines = len(entries), len(entries)
+	return nil
+}
+
+func (j *Journal) write(line string) error {
+	if _, err := j.writer.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write the journal: %v", err)
+	}
+	if err := j.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush the journal: %v", err)
+	}
+	return nil
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.writer.Flush(); err != nil {
+		return err
+	}
+	return j.file.Close()
+}
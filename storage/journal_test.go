@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalReplaysThePendingElementsInOrder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for seq, data := range []string{"first", "second", "third"} {
+		if err := journal.Append(uint64(seq+1), []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := journal.Done(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	journal, err = OpenJournal(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	entries, err := journal.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || string(entries[0]) != "first" || string(entries[1]) != "third" {
+		t.Fatalf("replayed %q, want [first third]", entries)
+	}
+
+	// The replayed elements stay pending under their position until done
+	if err := journal.Done(1); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := journal.Replay(); err != nil || len(entries) != 1 || string(entries[0]) != "third" {
+		t.Errorf("replayed %q again, %v, want [third]", entries, err)
+	}
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("replacement of the journal left aside: %v", err)
+	}
+}
+
+func TestJournalIsTruncatedOnceNothingIsPending(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	if err := journal.Append(1, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Append(2, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Done(1); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filename); err != nil || info.Size() == 0 {
+		t.Fatalf("journal emptied while an element is pending: %v", err)
+	}
+
+	if err := journal.Done(2); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filename); err != nil || info.Size() != 0 {
+		t.Errorf("journal not truncated once nothing is pending: %v", err)
+	}
+
+	// Appending after the truncation writes at the start of the file again
+	if err := journal.Append(3, []byte("third")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := journal.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || string(entries[0]) != "third" {
+		t.Errorf("replayed %q, want [third]", entries)
+	}
+}
+
+func TestJournalIsCompactedDownToThePendingElements(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	// A dead letter stays pending while the next elements are processed
+	if err := journal.Append(1, []byte("letter")); err != nil {
+		t.Fatal(err)
+	}
+	for seq := uint64(2); seq < 10*compaction; seq++ {
+		if err := journal.Append(seq, []byte("element")); err != nil {
+			t.Fatal(err)
+		}
+		if err := journal.Done(seq); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, err := os.Stat(filename); err != nil || info.Size() > int64(2*compaction*len("+10000 element\n")) {
+		t.Fatalf("journal not compacted: %v, %v", info.Size(), err)
+	}
+
+	entries, err := journal.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || string(entries[0]) != "letter" {
+		t.Errorf("replayed %q, want [letter]", entries)
+	}
+}
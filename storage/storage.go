@@ -3,6 +3,7 @@ package storage
 import (
 	"bufio"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -12,16 +13,25 @@ type Storage struct {
 	filename string
 	logs     *os.File
 	writer   *bufio.Writer
-	input    chan string
+	input    chan entry
 	wg       sync.WaitGroup
 	quit     chan struct{}
 }
 
+// entry is a log to write, with the channel receiving the result of the
+// write when it is committed.
+type entry struct {
+	log  string
+	done chan error
+}
+
+var errClosed = errors.New("storage is closed")
+
 func NewStorage(filename string) *Storage {
 
 	storage := &Storage{
 		filename: filename,
-		input:    make(chan string, 100),
+		input:    make(chan entry, 100),
 		quit:     make(chan struct{}),
 	}
 
@@ -89,7 +99,23 @@ func (s *Storage) Load() ([]string, error) {
 }
 
 func (s *Storage) Append(log string) {
-	s.input <- log
+	s.input <- entry{log: log}
+}
+
+// Commit appends the log and waits until it is written to the file.
+func (s *Storage) Commit(log string) error {
+	done := make(chan error, 1)
+	select {
+	case s.input <- entry{log: log, done: done}:
+	case <-s.quit:
+		return errClosed
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-s.quit:
+		return errClosed
+	}
 }
 
 func (s *Storage) Stream(start int) <-chan string {
@@ -145,12 +171,13 @@ func (s *Storage) Start() error {
 
 	for {
 		select {
-		case log := <-s.input:
-			if _, err := s.writer.WriteString(log + "\n"); err != nil {
-				return fmt.Errorf("failed to write data: %v", err)
+		case e := <-s.input:
+			err := s.write(e.log)
+			if e.done != nil {
+				e.done <- err
 			}
-			if err := s.writer.Flush(); err != nil {
-				return fmt.Errorf("failed to flush buffer: %v", err)
+			if err != nil {
+				return err
 			}
 		case <-s.quit:
 			if err := s.writer.Flush(); err != nil {
@@ -161,6 +188,16 @@ func (s *Storage) Start() error {
 	}
 }
 
+func (s *Storage) write(log string) error {
+	if _, err := s.writer.WriteString(log + "\n"); err != nil {
+		return fmt.Errorf("failed to write data: %v", err)
+	}
+	if err := s.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush buffer: %v", err)
+	}
+	return nil
+}
+
 func (s *Storage) Close() {
 	close(s.quit)
 	s.wg.Wait()
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// LoadTokens reads the grants of the clients from a JSON file listing
+// {"name", "token", "scopes"} objects.
+func LoadTokens(filename string) (*domain.Tokens, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tokens file: %v", err)
+	}
+
+	var grants []domain.Grant
+	if err := json.Unmarshal(data, &grants); err != nil {
+		return nil, fmt.Errorf("error decoding tokens file: %v", err)
+	}
+
+	return domain.NewTokens(grants)
+}
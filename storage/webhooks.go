@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// LoadWebhooks reads the webhooks registered on the node from a JSON file, no
+// webhook being registered when it does not exist yet.
+func LoadWebhooks(filename string) ([]domain.Webhook, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading webhooks file: %v", err)
+	}
+
+	var hooks []domain.Webhook
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("error decoding webhooks file: %v", err)
+	}
+	return hooks, nil
+}
+
+// SaveWebhooks writes the webhooks, with their secrets, readable by the owner
+// of the file only.
+func SaveWebhooks(filename string, hooks []domain.Webhook) error {
+	data, err := json.MarshalIndent(hooks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp := filename + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return fmt.Errorf("error writing webhooks file: %v", err)
+	}
+	return os.Rename(temp, filename)
+}
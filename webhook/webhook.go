@@ -0,0 +1,304 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// pending is the number of events a webhook may fall behind before the next
+// ones are dropped.
+const pending = 1024
+
+type Service interface {
+	Subscribe(context.Context, string, string, []string) (<-chan domain.Event, error)
+}
+
+// Registry delivers the events of the network to the webhooks registered on
+// the node.
+type Registry struct {
+	Service  Service
+	Client   *http.Client
+	attempts int
+	backoff  time.Duration
+	save     func([]domain.Webhook) error
+	private  bool
+	mu       *sync.Mutex
+	next     int
+	hooks    map[string]*hook
+}
+
+type hook struct {
+	webhook    domain.Webhook
+	deliveries domain.Deliveries
+	events     chan domain.Event
+	cancel     context.CancelFunc
+}
+
+// NewRegistry starts the delivery of the webhooks, an event being posted up
+// to attempts times with a backoff doubled on every retry. The webhooks are
+// given to save whenever they change, if it is not nil. Unless private is
+// true, webhooks may not reach loopback, link-local or private addresses.
+func NewRegistry(service Service, hooks []domain.Webhook, attempts int, backoff time.Duration, save func([]domain.Webhook) error, private bool) (*Registry, error) {
+	r := &Registry{
+		Service:  service,
+		Client:   &http.Client{Timeout: 10 * time.Second, Transport: transport(private)},
+		attempts: max(attempts, 1),
+		backoff:  backoff,
+		save:     save,
+		private:  private,
+		mu:       &sync.Mutex{},
+		hooks:    make(map[string]*hook),
+	}
+
+	for _, webhook := range hooks {
+		if err := webhook.Validate(private); err != nil {
+			return nil, fmt.Errorf("webhook %s: %w", webhook.Id, err)
+		}
+		if n, err := strconv.Atoi(webhook.Id); err == nil {
+			r.next = max(r.next, n)
+		}
+		r.start(webhook)
+	}
+	return r, nil
+}
+
+// Add registers the webhook under a new id, returned.
+func (r *Registry) Add(webhook domain.Webhook) (domain.Webhook, error) {
+	if err := webhook.Validate(r.private); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	r.mu.Lock()
+	r.next++
+	webhook.Id = strconv.Itoa(r.next)
+	r.mu.Unlock()
+
+	r.start(webhook)
+	return webhook, r.persist()
+}
+
+// Remove stops and unregisters the webhook.
+func (r *Registry) Remove(id string) (bool, error) {
+	r.mu.Lock()
+	h, exist := r.hooks[id]
+	delete(r.hooks, id)
+	r.mu.Unlock()
+
+	if !exist {
+		return false, nil
+	}
+	h.cancel()
+	return true, r.persist()
+}
+
+// Deliveries returns the state of the deliveries of every webhook by id.
+func (r *Registry) Deliveries() map[string]domain.Deliveries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make(map[string]domain.Deliveries, len(r.hooks))
+	for id, h := range r.hooks {
+		deliveries := h.deliveries
+		deliveries.Pending = len(h.events)
+		list[id] = deliveries
+	}
+	return list
+}
+
+// Webhooks returns the webhooks registered, without their secrets.
+func (r *Registry) Webhooks() []domain.Webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhooks := make([]domain.Webhook, 0, len(r.hooks))
+	for _, h := range r.hooks {
+		webhook := h.webhook
+		webhook.Secret = ""
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return order(webhooks[i].Id) < order(webhooks[j].Id) })
+	return webhooks
+}
+
+// transport dials the webhooks, refusing the internal addresses a name
+// resolves to unless private is true.
+func transport(private bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	if !private {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if domain.Internal(net.ParseIP(host)) {
+				return fmt.Errorf("%w: %s is an internal address", domain.ErrForbidden, host)
+			}
+			return nil
+		}
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+func order(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
+
+// Close stops the delivery of the webhooks.
+func (r *Registry) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, h := range r.hooks {
+		h.cancel()
+	}
+}
+
+func (r *Registry) start(webhook domain.Webhook) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &hook{webhook: webhook, events: make(chan domain.Event, pending), cancel: cancel}
+
+	r.mu.Lock()
+	r.hooks[webhook.Id] = h
+	r.mu.Unlock()
+
+	go r.listen(ctx, h)
+	go r.deliver(ctx, h)
+}
+
+func (r *Registry) persist() error {
+	if r.save == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	webhooks := make([]domain.Webhook, 0, len(r.hooks))
+	for _, h := range r.hooks {
+		webhooks = append(webhooks, h.webhook)
+	}
+	r.mu.Unlock()
+
+	return r.save(webhooks)
+}
+
+// listen subscribes to the events of the webhook, again after a backoff when
+// the subscription ends.
+func (r *Registry) listen(ctx context.Context, h *hook) {
+	for ctx.Err() == nil {
+		events, err := r.Service.Subscribe(ctx, h.webhook.Collection, h.webhook.Location, nil)
+		if err != nil {
+			log.Printf("Error subscribing webhook %s: %v", h.webhook.Id, err)
+		} else {
+			for event := range events {
+				select {
+				case h.events <- event:
+				default:
+					r.update(h, func(d *domain.Deliveries) { d.Dropped++ })
+				}
+			}
+		}
+
+		select {
+		case <-time.After(r.backoff):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// deliver posts the events of the webhook in order.
+func (r *Registry) deliver(ctx context.Context, h *hook) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-h.events:
+			r.post(ctx, h, event)
+		}
+	}
+}
+
+// post sends the event, retrying with backoff until it is accepted or runs out
+// of attempts. Client errors but timeouts and rate limits are not retried.
+func (r *Registry) post(ctx context.Context, h *hook, event domain.Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		status, err := r.send(ctx, h.webhook, event, payload)
+		r.update(h, func(d *domain.Deliveries) {
+			d.LastStatus, d.LastTime, d.LastError = status, time.Now(), ""
+			if err != nil {
+				d.LastError = err.Error()
+			}
+		})
+		if err == nil {
+			r.update(h, func(d *domain.Deliveries) { d.Delivered++ })
+			return
+		}
+
+		final := status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+		if final || attempt >= r.attempts {
+			r.update(h, func(d *domain.Deliveries) { d.Failed++ })
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Registry) send(ctx context.Context, webhook domain.Webhook, event domain.Event, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Indexus-Event", event.Type)
+	req.Header.Set("X-Indexus-Webhook", webhook.Id)
+	// Every attempt is signed at its time, so that receivers refuse old ones
+	timestamp := time.Now().Unix()
+	req.Header.Set(domain.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(domain.WebhookSignatureHeader, webhook.Sign(timestamp, payload))
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (r *Registry) update(h *hook, change func(*domain.Deliveries)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(&h.deliveries)
+}
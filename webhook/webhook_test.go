@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// receiver answers the deliveries with the statuses in turn, the last one
+// repeated, and records the number of deliveries and the last ones received.
+type receiver struct {
+	statuses  []int
+	count     atomic.Int32
+	signature atomic.Value
+	timestamp atomic.Value
+	payload   atomic.Value
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.payload.Store(body)
+	rc.signature.Store(r.Header.Get(domain.WebhookSignatureHeader))
+	rc.timestamp.Store(r.Header.Get(domain.WebhookTimestampHeader))
+
+	n := int(rc.count.Add(1))
+	w.WriteHeader(rc.statuses[min(n, len(rc.statuses))-1])
+}
+
+func post(t *testing.T, attempts int, statuses ...int) (*receiver, domain.Deliveries, domain.Webhook) {
+	t.Helper()
+
+	rc := &receiver{statuses: statuses}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	// The test server listens on a loopback address
+	registry, err := NewRegistry(nil, nil, attempts, time.Millisecond, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook := domain.Webhook{Id: "1", Collection: "c", Location: "", URL: server.URL, Secret: "secret"}
+	h := &hook{webhook: webhook}
+
+	registry.post(context.Background(), h, domain.Event{Type: domain.Added, Item: &domain.Item{Collection: "c", Id: "i"}})
+	return rc, h.deliveries, webhook
+}
+
+func TestPostSignsTheEvent(t *testing.T) {
+	rc, deliveries, webhook := post(t, 3, http.StatusOK)
+
+	if deliveries.Delivered != 1 || deliveries.Failed != 0 || deliveries.LastStatus != http.StatusOK {
+		t.Errorf("deliveries %+v", deliveries)
+	}
+	payload, _ := rc.payload.Load().([]byte)
+	timestamp, err := strconv.ParseInt(rc.timestamp.Load().(string), 10, 64)
+	if err != nil || time.Since(time.Unix(timestamp, 0)) > domain.WebhookTolerance {
+		t.Fatalf("timestamp %v, %v", rc.timestamp.Load(), err)
+	}
+	if signature := rc.signature.Load(); signature != webhook.Sign(timestamp, payload) {
+		t.Errorf("signature %v, want %s", signature, webhook.Sign(timestamp, payload))
+	}
+	if webhook.Sign(timestamp+1, payload) == webhook.Sign(timestamp, payload) {
+		t.Error("signature does not cover the timestamp")
+	}
+}
+
+func TestPostRetriesTimeoutsRateLimitsAndServerErrors(t *testing.T) {
+	rc, deliveries, _ := post(t, 4, http.StatusTooManyRequests, http.StatusRequestTimeout, http.StatusBadGateway, http.StatusOK)
+
+	if rc.count.Load() != 4 || deliveries.Delivered != 1 || deliveries.Failed != 0 {
+		t.Errorf("delivered after %d attempts: %+v", rc.count.Load(), deliveries)
+	}
+}
+
+func TestPostGivesUpOnClientErrors(t *testing.T) {
+	rc, deliveries, _ := post(t, 4, http.StatusBadRequest)
+
+	if rc.count.Load() != 1 || deliveries.Failed != 1 || deliveries.LastStatus != http.StatusBadRequest {
+		t.Errorf("failed after %d attempts: %+v", rc.count.Load(), deliveries)
+	}
+}
+
+func TestPostGivesUpAfterTheAttempts(t *testing.T) {
+	rc, deliveries, _ := post(t, 3, http.StatusInternalServerError)
+
+	if rc.count.Load() != 3 || deliveries.Failed != 1 || deliveries.Delivered != 0 || len(deliveries.LastError) == 0 {
+		t.Errorf("failed after %d attempts: %+v", rc.count.Load(), deliveries)
+	}
+}
+
+func TestPrivateAddressesAreRefused(t *testing.T) {
+	server := httptest.NewServer(&receiver{statuses: []int{http.StatusOK}})
+	defer server.Close()
+
+	registry, err := NewRegistry(nil, nil, 1, time.Millisecond, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook := domain.Webhook{Collection: "c", URL: server.URL, Secret: "secret"}
+	if _, err := registry.Add(webhook); err == nil {
+		t.Error("webhook reaching a loopback address registered")
+	}
+
+	// Names resolving to internal addresses are refused when dialing
+	_, err = registry.send(context.Background(), webhook, domain.Event{Type: domain.Added}, []byte("{}"))
+	if err == nil {
+		t.Error("delivery to a loopback address sent")
+	}
+}
@@ -0,0 +1,627 @@
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+var errShort = errors.New("payload is truncated")
+
+// Contact is a peer as transmitted by the protocol.
+type Contact struct {
+	Name  string
+	IPs   map[string]any
+	IP    string
+	Port  int
+	Load  domain.Load
+	Proof *domain.Proof
+}
+
+// Encoder appends values to a payload, encoded for the version of the
+// protocol.
+type Encoder struct {
+	buf     []byte
+	version int
+}
+
+func NewEncoder() *Encoder {
+	return NewEncoderAt(Version)
+}
+
+// NewEncoderAt returns an encoder for the version negotiated with a peer.
+func NewEncoderAt(version int) *Encoder {
+	return &Encoder{buf: make([]byte, 0, 64), version: version}
+}
+
+// Version returns the version of the protocol of the payload.
+func (e *Encoder) Version() int {
+	return e.version
+}
+
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) Int(v int) {
+	e.buf = binary.AppendVarint(e.buf, int64(v))
+}
+
+func (e *Encoder) Uint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *Encoder) Float(v float64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *Encoder) Bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *Encoder) String(v string) {
+	e.Uint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *Encoder) Strings(values []string) {
+	e.Uint(uint64(len(values)))
+	for _, v := range values {
+		e.String(v)
+	}
+}
+
+// Hops encodes the nodes an insertion went through, from the version
+// carrying them.
+func (e *Encoder) Hops(hops []string) {
+	if e.version >= hopping {
+		e.Strings(hops)
+	}
+}
+
+func (e *Encoder) Load(load domain.Load) {
+	e.Int(load.Items)
+	e.Float(load.Rate)
+	e.Int(load.Queue)
+}
+
+func (e *Encoder) Contact(contact domain.Contact) {
+	e.String(contact.Name())
+	e.Uint(uint64(len(contact.IPs())))
+	for ip := range contact.IPs() {
+		e.String(ip)
+	}
+	e.String(contact.IP())
+	e.Int(contact.Port())
+	e.Load(contact.Load())
+
+	var proof *domain.Proof
+	if prover, ok := contact.(domain.Prover); ok {
+		proof = prover.Proof()
+	}
+	e.Proof(proof)
+}
+
+// Proof encodes a proof of work, nil included.
+func (e *Encoder) Proof(proof *domain.Proof) {
+	e.Bool(proof != nil)
+	if proof == nil {
+		return
+	}
+	e.String(string(proof.Key))
+	e.Uint(proof.Nonce)
+}
+
+func (e *Encoder) Contacts(contacts []domain.Contact) {
+	e.Uint(uint64(len(contacts)))
+	for _, contact := range contacts {
+		e.Contact(contact)
+	}
+}
+
+func (e *Encoder) Key(key domain.Key) {
+	e.String(key.Collection)
+	e.String(key.Location)
+}
+
+func (e *Encoder) Policy(policy domain.Policy) {
+	e.Int(policy.Delegation)
+	e.Int(policy.SetLength)
+	if e.version < writers {
+		return
+	}
+	e.String(policy.Access)
+	e.String(string(policy.Owner))
+	e.Uint(uint64(len(policy.Writers)))
+	for _, writer := range policy.Writers {
+		e.String(string(writer))
+	}
+}
+
+func (e *Encoder) Item(item *domain.Item) {
+	e.String(item.Collection)
+	e.String(item.Location)
+	e.String(item.Id)
+	if e.version >= writers {
+		e.String(string(item.Writer))
+		e.String(string(item.Signature))
+	}
+	if e.version >= idempotent {
+		e.String(item.Idempotency)
+	}
+}
+
+func (e *Encoder) Items(items []*domain.Item) {
+	e.Uint(uint64(len(items)))
+	for _, item := range items {
+		e.Item(item)
+	}
+}
+
+// Witnesses encodes the idempotency keys of an area handed over, from the
+// witnessing version.
+func (e *Encoder) Witnesses(witnesses []domain.Witness) {
+	if e.version < witnessing {
+		return
+	}
+	e.Uint(uint64(len(witnesses)))
+	for _, witness := range witnesses {
+		e.String(witness.Key)
+		e.String(witness.Area)
+		e.Int(int(witness.Time.UnixNano()))
+	}
+}
+
+// Redirects encodes the areas handed to peers, none before the version
+// carrying them.
+func (e *Encoder) Redirects(redirects []domain.Redirect) {
+	if e.version < redirecting {
+		return
+	}
+	e.Uint(uint64(len(redirects)))
+	for _, redirect := range redirects {
+		e.Key(redirect.Key)
+		e.Contact(redirect.Contact)
+	}
+}
+
+func (e *Encoder) Insertions(insertions []*domain.Insertion) {
+	e.Uint(uint64(len(insertions)))
+	for _, insertion := range insertions {
+		e.Item(insertion.Item)
+		e.Policy(insertion.Policy)
+		e.String(insertion.Root)
+		e.String(insertion.Current)
+		e.Hops(insertion.Hops)
+	}
+}
+
+// Error encodes the kind and the message of the error, nil included.
+func (e *Encoder) Error(err error) {
+	e.String(domain.Kind(err))
+	if err != nil {
+		e.String(err.Error())
+	} else {
+		e.String("")
+	}
+}
+
+func (e *Encoder) Errors(errs []error) {
+	e.Uint(uint64(len(errs)))
+	for _, err := range errs {
+		e.Error(err)
+	}
+}
+
+// Signature encodes a signature, nil included.
+func (e *Encoder) Signature(signature *domain.Signature) {
+	e.Bool(signature != nil)
+	if signature == nil {
+		return
+	}
+	e.String(signature.Name)
+	e.String(string(signature.Key))
+	e.Uint(signature.Nonce)
+	e.Int(int(signature.Timestamp))
+	e.String(string(signature.Value))
+}
+
+// Set encodes a set, nil included.
+func (e *Encoder) Set(set *domain.Set) {
+	e.Bool(set != nil)
+	if set == nil {
+		return
+	}
+	if e.version >= versioned {
+		e.Uint(set.Version())
+	}
+	list := set.List()
+	e.Uint(uint64(len(list)))
+	for key, count := range list {
+		e.String(key)
+		e.Int(count)
+	}
+}
+
+// Since encodes the version of a set known to the caller, from the version
+// carrying it.
+func (e *Encoder) Since(version uint64) {
+	if e.version >= versioned {
+		e.Uint(version)
+	}
+}
+
+// Unchanged encodes domain.ErrNotModified when the set is still at the version
+// known to the caller, from the version carrying it.
+func (e *Encoder) Unchanged(err error) {
+	if e.version >= versioned {
+		e.Error(err)
+	}
+}
+
+func (e *Encoder) Delta(delta *domain.Delta) {
+	e.Uint(delta.Since)
+	e.Uint(delta.Version)
+	e.Uint(uint64(len(delta.Changes)))
+	for key, count := range delta.Changes {
+		e.String(key)
+		e.Int(count)
+	}
+	e.Strings(delta.Removed)
+}
+
+// Decoder reads the values of a payload in the order they were encoded for
+// the version of the protocol. The first error is kept and returned by Err,
+// later reads return zero values.
+type Decoder struct {
+	buf     []byte
+	err     error
+	version int
+}
+
+func NewDecoder(payload []byte) *Decoder {
+	return NewDecoderAt(payload, Version)
+}
+
+// NewDecoderAt returns a decoder for the version negotiated with a peer.
+func NewDecoderAt(payload []byte, version int) *Decoder {
+	return &Decoder{buf: payload, version: version}
+}
+
+// Version returns the version of the protocol of the payload.
+func (d *Decoder) Version() int {
+	return d.version
+}
+
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Rest returns the bytes left to read.
+func (d *Decoder) Rest() []byte {
+	if d.err != nil {
+		return nil
+	}
+	return d.buf
+}
+
+func (d *Decoder) Int() int {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errShort
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return int(v)
+}
+
+func (d *Decoder) Uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errShort
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *Decoder) Float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 8 {
+		d.err = errShort
+		return 0
+	}
+	v := math.Float64frombits(binary.BigEndian.Uint64(d.buf))
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *Decoder) Bool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.buf) < 1 {
+		d.err = errShort
+		return false
+	}
+	v := d.buf[0] == 1
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *Decoder) String() string {
+	length := d.Uint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.buf)) < length {
+		d.err = errShort
+		return ""
+	}
+	v := string(d.buf[:length])
+	d.buf = d.buf[length:]
+	return v
+}
+
+// count reads a length and checks it against the remaining bytes, every
+// element taking at least one byte.
+func (d *Decoder) count() int {
+	length := d.Uint()
+	if d.err == nil && length > uint64(len(d.buf)) {
+		d.err = errShort
+		return 0
+	}
+	return int(length)
+}
+
+func (d *Decoder) Strings() []string {
+	length := d.count()
+	values := make([]string, 0, length)
+	for i := 0; i < length && d.err == nil; i++ {
+		values = append(values, d.String())
+	}
+	return values
+}
+
+// Hops decodes the nodes an insertion went through, none before the version
+// carrying them.
+func (d *Decoder) Hops() []string {
+	if d.version < hopping {
+		return nil
+	}
+	return d.Strings()
+}
+
+func (d *Decoder) Load() domain.Load {
+	return domain.Load{
+		Items: d.Int(),
+		Rate:  d.Float(),
+		Queue: d.Int(),
+	}
+}
+
+func (d *Decoder) Contact() Contact {
+	contact := Contact{Name: d.String(), IPs: make(map[string]any)}
+	for i, length := 0, d.count(); i < length; i++ {
+		contact.IPs[d.String()] = nil
+	}
+	contact.IP = d.String()
+	contact.Port = d.Int()
+	contact.Load = d.Load()
+	contact.Proof = d.Proof()
+	return contact
+}
+
+func (d *Decoder) Proof() *domain.Proof {
+	if !d.Bool() {
+		return nil
+	}
+	proof := &domain.Proof{
+		Key:   []byte(d.String()),
+		Nonce: d.Uint(),
+	}
+	if d.err != nil {
+		return nil
+	}
+	return proof
+}
+
+func (d *Decoder) Contacts() []Contact {
+	length := d.count()
+	contacts := make([]Contact, 0, length)
+	for i := 0; i < length && d.err == nil; i++ {
+		contacts = append(contacts, d.Contact())
+	}
+	return contacts
+}
+
+func (d *Decoder) Key() domain.Key {
+	return domain.Key{
+		Collection: d.String(),
+		Location:   d.String(),
+	}
+}
+
+func (d *Decoder) Policy() domain.Policy {
+	policy := domain.Policy{
+		Delegation: d.Int(),
+		SetLength:  d.Int(),
+	}
+	if d.version < writers {
+		return policy
+	}
+	policy.Access, policy.Owner = d.String(), d.bytes()
+	for i, length := 0, d.count(); i < length && d.err == nil; i++ {
+		policy.Writers = append(policy.Writers, d.bytes())
+	}
+	return policy
+}
+
+func (d *Decoder) Item() *domain.Item {
+	item := &domain.Item{
+		Collection: d.String(),
+		Location:   d.String(),
+		Id:         d.String(),
+	}
+	if d.version >= writers {
+		item.Writer, item.Signature = d.bytes(), d.bytes()
+	}
+	if d.version >= idempotent {
+		item.Idempotency = d.String()
+	}
+	return item
+}
+
+// bytes reads a string as bytes, nil when empty.
+func (d *Decoder) bytes() []byte {
+	v := d.String()
+	if len(v) == 0 {
+		return nil
+	}
+	return []byte(v)
+}
+
+func (d *Decoder) Items() []*domain.Item {
+	length := d.count()
+	items := make([]*domain.Item, 0, length)
+	for i := 0; i < length && d.err == nil; i++ {
+		items = append(items, d.Item())
+	}
+	return items
+}
+
+func (d *Decoder) Witnesses() []domain.Witness {
+	if d.version < witnessing {
+		return nil
+	}
+	length := d.count()
+	witnesses := make([]domain.Witness, 0, length)
+	for i := 0; i < length && d.err == nil; i++ {
+		witnesses = append(witnesses, domain.Witness{
+			Key:  d.String(),
+			Area: d.String(),
+			Time: time.Unix(0, int64(d.Int())),
+		})
+	}
+	return witnesses
+}
+
+// Redirect is an area handed to a peer, as decoded.
+type Redirect struct {
+	Key     domain.Key
+	Contact Contact
+}
+
+func (d *Decoder) Redirects() []Redirect {
+	if d.version < redirecting {
+		return nil
+	}
+	length := d.count()
+	redirects := make([]Redirect, 0, length)
+	for i := 0; i < length && d.err == nil; i++ {
+		redirects = append(redirects, Redirect{Key: d.Key(), Contact: d.Contact()})
+	}
+	return redirects
+}
+
+func (d *Decoder) Insertions() []*domain.Insertion {
+	length := d.count()
+	insertions := make([]*domain.Insertion, 0, length)
+	for i := 0; i < length && d.err == nil; i++ {
+		insertions = append(insertions, &domain.Insertion{
+			Item:    d.Item(),
+			Policy:  d.Policy(),
+			Root:    d.String(),
+			Current: d.String(),
+			Hops:    d.Hops(),
+		})
+	}
+	return insertions
+}
+
+func (d *Decoder) Error() error {
+	return domain.KindError(d.String(), d.String())
+}
+
+func (d *Decoder) Errors() []error {
+	length := d.count()
+	errs := make([]error, 0, length)
+	for i := 0; i < length && d.err == nil; i++ {
+		errs = append(errs, d.Error())
+	}
+	return errs
+}
+
+func (d *Decoder) Set() *domain.Set {
+	if !d.Bool() {
+		return nil
+	}
+	set, version := domain.NewSet(), uint64(0)
+	if d.version >= versioned {
+		version = d.Uint()
+	}
+	for i, length := 0, d.count(); i < length && d.err == nil; i++ {
+		set.Put(d.String(), d.Int())
+	}
+	set.SetVersion(version)
+	return set
+}
+
+// Since decodes the version of a set known to the caller, 0 before the
+// version carrying it.
+func (d *Decoder) Since() uint64 {
+	if d.version < versioned {
+		return 0
+	}
+	return d.Uint()
+}
+
+// Unchanged decodes domain.ErrNotModified when the set is still at the version
+// known to the caller, nil before the version carrying it.
+func (d *Decoder) Unchanged() error {
+	if d.version < versioned {
+		return nil
+	}
+	return d.Error()
+}
+
+func (d *Decoder) Delta() *domain.Delta {
+	delta := &domain.Delta{Since: d.Uint(), Version: d.Uint(), Changes: make(map[string]int)}
+	for i, length := 0, d.count(); i < length && d.err == nil; i++ {
+		delta.Changes[d.String()] = d.Int()
+	}
+	delta.Removed = d.Strings()
+	return delta
+}
+
+func (d *Decoder) Signature() *domain.Signature {
+	if !d.Bool() {
+		return nil
+	}
+	signature := &domain.Signature{
+		Name:      d.String(),
+		Key:       []byte(d.String()),
+		Nonce:     d.Uint(),
+		Timestamp: int64(d.Int()),
+		Value:     []byte(d.String()),
+	}
+	if d.err != nil {
+		return nil
+	}
+	return signature
+}
@@ -0,0 +1,68 @@
+package wire
+
+import (
+	"testing"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+func TestItemFollowsVersion(t *testing.T) {
+	item := &domain.Item{Collection: "c", Location: "l", Id: "i", Writer: []byte("w"), Signature: []byte("s"), Idempotency: "k"}
+
+	for _, version := range []int{MinVersion, writers, idempotent, Version} {
+		e := NewEncoderAt(version)
+		e.Item(item)
+		e.Hops([]string{"a"})
+		e.String("end")
+
+		d := NewDecoderAt(e.Bytes(), version)
+		decoded, hops, end := d.Item(), d.Hops(), d.String()
+		if err := d.Err(); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if end != "end" {
+			t.Fatalf("version %d: decoded %q instead of the trailing value", version, end)
+		}
+		if got, want := len(decoded.Writer) > 0, version >= writers; got != want {
+			t.Errorf("version %d: writer decoded %v, want %v", version, got, want)
+		}
+		if got, want := decoded.Idempotency == "k", version >= idempotent; got != want {
+			t.Errorf("version %d: idempotency decoded %v, want %v", version, got, want)
+		}
+		if got, want := len(hops) == 1, version >= hopping; got != want {
+			t.Errorf("version %d: hops decoded %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestSetFollowsVersion(t *testing.T) {
+	set := domain.NewSet()
+	set.Put("a", 2)
+	set.SetVersion(42)
+
+	for _, version := range []int{idempotent, versioned} {
+		e := NewEncoderAt(version)
+		e.Since(7)
+		e.Set(set)
+
+		d := NewDecoderAt(e.Bytes(), version)
+		since, decoded := d.Since(), d.Set()
+		if err := d.Err(); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if decoded.List()["a"] != 2 {
+			t.Errorf("version %d: entries %v", version, decoded.List())
+		}
+
+		want := uint64(0)
+		if version >= versioned {
+			want = 42
+		}
+		if decoded.Version() != want {
+			t.Errorf("version %d: set version %d, want %d", version, decoded.Version(), want)
+		}
+		if version >= versioned && since != 7 || version < versioned && since != 0 {
+			t.Errorf("version %d: since %d", version, since)
+		}
+	}
+}
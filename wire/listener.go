@@ -0,0 +1,107 @@
+package wire
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// handshake is the first byte of a TLS connection.
+const handshake = 0x16
+
+// conn replays the bytes peeked while sniffing the protocol.
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *conn) NetConn() net.Conn {
+	return c.Conn
+}
+
+type listener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	close func() error
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *listener) Close() error {
+	return l.close()
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+// Split shares a listener between the HTTP transport and the binary
+// protocol, dispatching every connection on its first bytes. Connections
+// opening with a TLS handshake are decrypted first when config is set.
+func Split(lis net.Listener, config *tls.Config) (net.Listener, net.Listener) {
+	done := make(chan struct{})
+
+	once := &sync.Once{}
+	shutdown := func() error {
+		var err error
+		once.Do(func() {
+			close(done)
+			err = lis.Close()
+		})
+		return err
+	}
+
+	plain := &listener{addr: lis.Addr(), conns: make(chan net.Conn), done: done, close: shutdown}
+	framed := &listener{addr: lis.Addr(), conns: make(chan net.Conn), done: done, close: shutdown}
+
+	go func() {
+		defer shutdown()
+		for {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go dispatch(c, config, plain, framed)
+		}
+	}()
+
+	return plain, framed
+}
+
+func dispatch(c net.Conn, config *tls.Config, plain, framed *listener) {
+	reader := bufio.NewReader(c)
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	first, err := reader.Peek(1)
+	if err == nil && first[0] == handshake && config != nil {
+		c = tls.Server(&conn{Conn: c, reader: reader}, config)
+		reader = bufio.NewReader(c)
+	}
+	peeked, err := reader.Peek(4)
+	c.SetReadDeadline(time.Time{})
+
+	target := plain
+	if err == nil && string(peeked) == Preface[:4] {
+		target = framed
+	}
+
+	select {
+	case target.conns <- &conn{Conn: c, reader: reader}:
+	case <-target.done:
+		c.Close()
+	}
+}
@@ -0,0 +1,474 @@
+package wire
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/indexus/go-indexus-core/domain"
+	"github.com/indexus/go-indexus-core/security"
+)
+
+const idle = 2 * time.Minute
+
+// replays is the number of signatures of the messages changing the node
+// remembered to refuse their replay.
+const replays = 100_000
+
+var errImpersonation = errors.New("origin does not match the signature")
+
+type Service interface {
+	Ping(context.Context, domain.Contact) (domain.Contact, error)
+	Neighbors(context.Context, domain.Peer) ([]domain.Contact, error)
+	Random(context.Context, domain.Peer) (domain.Contact, error)
+	Transfer(context.Context, domain.Peer, domain.Key, domain.Policy, []*domain.Item, []domain.Witness, []domain.Redirect) error
+	Adopt(context.Context, domain.Peer, domain.Key, domain.Policy, []*domain.Item, []domain.Witness, []domain.Redirect) error
+	Get(context.Context, string, string, uint64) (domain.Contact, *domain.Set, error)
+	Changes(context.Context, string, string, uint64) (domain.Contact, *domain.Delta, error)
+	New(context.Context, *domain.Item, domain.Policy, string, string, []string) error
+	Insert(context.Context, *domain.Item, domain.Policy, string, string, []string) (domain.Contact, string, error)
+	Batch(context.Context, []*domain.Insertion) ([]error, error)
+	Watch(domain.Peer, domain.Key)
+	Invalidate(context.Context, domain.Peer, domain.Key, *domain.Set) error
+	Known(domain.Peer) bool
+}
+
+type peer struct {
+	id   []byte
+	name string
+}
+
+func newPeer(name string) (*peer, error) {
+	id, err := domain.DecodeName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &peer{id: id, name: name}, nil
+}
+
+func (p *peer) ID() []byte {
+	return p.id
+}
+
+func (p *peer) Name() string {
+	return p.name
+}
+
+// authorize returns the origin of a request, which must be the node which
+// signed it.
+func authorize(signer, name string) (*peer, error) {
+	if name != signer {
+		return nil, errImpersonation
+	}
+	return newPeer(name)
+}
+
+type Handler struct {
+	Service    Service
+	NewContact func(string, map[string]any, int) domain.Contact
+	Identity   *domain.Identity
+	secure     bool
+	mutual     bool
+	difficulty int
+	reserved   bool
+	limiters   map[Type]*domain.Limiter
+	replays    *domain.Replays
+}
+
+// New - Create a binary protocol handler
+func NewHandler(service Service, newContact func(string, map[string]any, int) domain.Contact, identity *domain.Identity) *Handler {
+	return &Handler{
+		Service:    service,
+		NewContact: newContact,
+		Identity:   identity,
+		replays:    domain.NewReplays(replays),
+	}
+}
+
+// Secure advertises TLS in the handshake and requires it on the other
+// messages, with the identity certificate of the peer when mutual.
+func (h *Handler) Secure(mutual bool) {
+	h.secure = true
+	h.mutual = mutual
+}
+
+// Restrict requires the proof of work of the difficulty from the signers of
+// the messages following the handshake.
+func (h *Handler) Restrict(difficulty int) {
+	h.difficulty = difficulty
+}
+
+// Reserve restricts the client messages to the peers registered by the node,
+// when the clients must present a bearer token to the HTTP endpoints.
+func (h *Handler) Reserve() {
+	h.reserved = true
+}
+
+// client reports whether the message is one of the client endpoints.
+func client(t Type) bool {
+	return t == Get || t == Changes || t == New || t == Insert || t == Batch
+}
+
+// mutating reports whether the message changes the node, so that it must not
+// be replayed. Two identical reads signed in the same second have the same
+// signature, and are harmless to repeat.
+func mutating(t Type) bool {
+	return t == New || t == Insert || t == Batch || t == Transfer || t == Adopt || t == Invalidate
+}
+
+// known reports whether the signer is a peer registered by the node. Without
+// difficulty anyone may register, so that no signer is known.
+func (h *Handler) known(signature *domain.Signature) bool {
+	if h.difficulty <= 0 || signature.Proof().Work() < h.difficulty {
+		return false
+	}
+	origin, err := newPeer(signature.Name)
+	return err == nil && h.Service.Known(origin)
+}
+
+// Limit sets the limiters of the read and write messages, a batch taking one
+// write per item, and of the peer maintenance ones, a nil limiter allowing
+// everything.
+func (h *Handler) Limit(read, write, peer *domain.Limiter) {
+	h.limiters = map[Type]*domain.Limiter{
+		Hello:      peer,
+		Neighbors:  peer,
+		Random:     peer,
+		Transfer:   peer,
+		Adopt:      peer,
+		Invalidate: peer,
+		Get:        read,
+		Changes:    read,
+		New:        write,
+		Insert:     write,
+		Batch:      write,
+	}
+}
+
+// limit takes a token from the budget of the message for the signer when it
+// is a known peer, or else for the IP of the connection, shared with the HTTP
+// calls.
+func (h *Handler) limit(t Type, signature *domain.Signature, c net.Conn, count int) error {
+	key := "peer:" + signature.Name
+	if !h.known(signature) {
+		host, _, _ := net.SplitHostPort(c.RemoteAddr().String())
+		key = "ip:" + host
+	}
+	if ok, wait := h.limiters[t].AllowN(key, count); !ok {
+		return fmt.Errorf("%w, retry after %s", domain.ErrRateLimited, wait)
+	}
+	return nil
+}
+
+// cost is the number of tokens taken by the message, one per item for a
+// batch.
+func cost(t Type, payload []byte, version int) int {
+	if t != Batch {
+		return 1
+	}
+	return max(NewDecoderAt(payload, version).count(), 1)
+}
+
+func (h *Handler) capabilities() Capabilities {
+	if h.secure {
+		return Supported
+	}
+	return Supported &^ Secure
+}
+
+// Serve - Run the binary protocol server
+func (h *Handler) Serve(lis net.Listener) error {
+
+	log.Println("P2P binary server started")
+
+	for {
+		c, err := lis.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go h.serve(c)
+	}
+}
+
+func (h *Handler) serve(c net.Conn) {
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := bufio.NewReader(c)
+	if err := ReadPreface(reader); err != nil {
+		return
+	}
+	if _, err := c.Write([]byte(Preface)); err != nil {
+		return
+	}
+
+	state := security.State(c)
+
+	// The version negotiated during the handshake, 0 before, and the length
+	// of the frames raised once it is signed with the work required
+	version := 0
+	limit := uint32(MaxHandshake)
+	for {
+		c.SetReadDeadline(time.Now().Add(idle))
+
+		t, frame, err := ReadFrame(reader, limit)
+		if err != nil {
+			return
+		}
+
+		var request []byte
+		var response []byte
+		signature, payload, err := Open(t, frame, nil)
+		if err == nil {
+			request = signature.Value
+			err = security.Tie(state, signature.Name)
+		}
+		switch {
+		case err != nil:
+		case t == Hello:
+			if err = h.limit(t, signature, c, 1); err == nil {
+				var negotiated int
+				response, negotiated, err = h.hello(ctx, c, signature, payload)
+				if err == nil {
+					version = negotiated
+					if signature.Proof().Work() >= h.difficulty {
+						limit = MaxFrame
+					}
+				}
+			}
+		case version == 0:
+			err = errors.New("handshake required")
+		case h.secure && state == nil:
+			err = errors.New("TLS required")
+		case h.mutual && len(security.Peer(state)) == 0:
+			err = errors.New("identity certificate required")
+		case signature.Proof().Work() < h.difficulty:
+			err = domain.ErrProof
+		case h.reserved && client(t) && !h.known(signature):
+			err = fmt.Errorf("%w: %s is not a known peer", domain.ErrUnauthorized, signature.Name)
+		default:
+			err = h.limit(t, signature, c, cost(t, payload, version))
+			if err == nil && mutating(t) {
+				err = h.replays.Check(signature)
+			}
+			if err == nil {
+				response, err = h.handle(ctx, signature.Name, t, payload, version)
+			}
+		}
+
+		if err != nil {
+			// The kind follows the message, older peers reading the message only
+			e := NewEncoder()
+			e.String(err.Error())
+			e.String(domain.Kind(err))
+			t, response = Error, e.Bytes()
+		} else {
+			t = Ok
+		}
+
+		frame, _ = Seal(h.Identity, t, response, request)
+		if err := WriteFrame(c, t, frame); err != nil {
+			return
+		}
+	}
+}
+
+// hello answers the handshake sent with Ping and negotiates the version, the
+// lowest of both sides, which the next messages of the connection are encoded
+// with. The origin must be the node which signed it.
+func (h *Handler) hello(ctx context.Context, c net.Conn, signature *domain.Signature, payload []byte) ([]byte, int, error) {
+	d := NewDecoder(payload)
+	version, capabilities, origin := d.Int(), Capabilities(d.Uint()), d.Contact()
+	if err := d.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	version = min(version, Version)
+	if version < MinVersion {
+		return nil, 0, fmt.Errorf("unsupported version: %d", version)
+	}
+
+	// An anonymous handshake only opens the connection for the next calls
+	if len(origin.Name) > 0 && origin.Name != signature.Name {
+		return nil, 0, errImpersonation
+	}
+
+	if host, _, err := net.SplitHostPort(c.RemoteAddr().String()); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			origin.IPs[ip.String()] = nil
+		}
+	}
+
+	contact, err := h.Service.Ping(ctx, domain.Prove(h.NewContact(origin.Name, origin.IPs, origin.Port), signature.Proof()))
+	if err != nil {
+		return nil, 0, err
+	}
+	if contact == nil {
+		return nil, 0, errors.New("contact not found")
+	}
+
+	e := NewEncoder()
+	e.Int(version)
+	e.Uint(uint64(capabilities & h.capabilities()))
+	e.Contact(contact)
+	return e.Bytes(), version, nil
+}
+
+func (h *Handler) handle(ctx context.Context, signer string, t Type, payload []byte, version int) ([]byte, error) {
+	d, e := NewDecoderAt(payload, version), NewEncoderAt(version)
+
+	switch t {
+	case Neighbors:
+		origin, err := authorize(signer, d.String())
+		if err != nil {
+			return nil, err
+		}
+		contacts, err := h.Service.Neighbors(ctx, origin)
+		if err != nil {
+			return nil, err
+		}
+		e.Contacts(contacts)
+
+	case Random:
+		origin, err := authorize(signer, d.String())
+		if err != nil {
+			return nil, err
+		}
+		random, err := h.Service.Random(ctx, origin)
+		if err != nil {
+			return nil, err
+		}
+		e.Bool(random != nil)
+		if random != nil {
+			e.Contact(random)
+		}
+
+	case Transfer, Adopt:
+		origin, err := authorize(signer, d.String())
+		if err != nil {
+			return nil, err
+		}
+		key, policy, items, witnesses, redirects := d.Key(), d.Policy(), d.Items(), d.Witnesses(), d.Redirects()
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		handed := make([]domain.Redirect, 0, len(redirects))
+		for _, redirect := range redirects {
+			contact := h.NewContact(redirect.Contact.Name, redirect.Contact.IPs, redirect.Contact.Port)
+			handed = append(handed, domain.Redirect{Key: redirect.Key, Contact: contact})
+		}
+		if t == Adopt {
+			err = h.Service.Adopt(ctx, origin, key, policy, items, witnesses, handed)
+		} else {
+			err = h.Service.Transfer(ctx, origin, key, policy, items, witnesses, handed)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+	case Get:
+		collection, location, known := d.String(), d.String(), d.Since()
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		contact, set, err := h.Service.Get(ctx, collection, location, known)
+		unchanged := errors.Is(err, domain.ErrNotModified)
+		if err != nil && !unchanged {
+			return nil, err
+		}
+		if origin, err := newPeer(signer); err == nil && (set != nil || unchanged) {
+			h.Service.Watch(origin, domain.Key{Collection: collection, Location: location})
+		}
+		e.Unchanged(err)
+		e.Contact(contact)
+		if !unchanged {
+			e.Set(set)
+		}
+
+	case Changes:
+		collection, location, known := d.String(), d.String(), d.Uint()
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		contact, delta, err := h.Service.Changes(ctx, collection, location, known)
+		unchanged := errors.Is(err, domain.ErrNotModified)
+		if err != nil && !unchanged {
+			return nil, err
+		}
+		if origin, err := newPeer(signer); err == nil && (delta != nil || unchanged) {
+			h.Service.Watch(origin, domain.Key{Collection: collection, Location: location})
+		}
+		e.Error(err)
+		e.Contact(contact)
+		if !unchanged {
+			e.Bool(delta != nil)
+			if delta != nil {
+				e.Delta(delta)
+			}
+		}
+
+	case Invalidate:
+		origin, err := authorize(signer, d.String())
+		if err != nil {
+			return nil, err
+		}
+		key, set := d.Key(), d.Set()
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		if err := h.Service.Invalidate(ctx, origin, key, set); err != nil {
+			return nil, err
+		}
+
+	case New:
+		item, policy, root, current, hops := d.Item(), d.Policy(), d.String(), d.String(), d.Hops()
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		if err := h.Service.New(ctx, item, policy, root, current, hops); err != nil {
+			return nil, err
+		}
+
+	case Insert:
+		item, policy, root, current, hops, wait := d.Item(), d.Policy(), d.String(), d.String(), d.Hops(), d.Int()
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		if wait > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(wait)*time.Millisecond)
+			defer cancel()
+		}
+		contact, location, err := h.Service.Insert(ctx, item, policy, root, current, hops)
+		e.Error(err)
+		if err == nil {
+			e.Contact(contact)
+			e.String(location)
+		}
+
+	case Batch:
+		insertions := d.Insertions()
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		results, err := h.Service.Batch(ctx, insertions)
+		if err != nil {
+			return nil, err
+		}
+		e.Errors(results)
+
+	default:
+		return nil, fmt.Errorf("unknown message type: %d", t)
+	}
+
+	return e.Bytes(), nil
+}
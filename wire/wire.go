@@ -0,0 +1,181 @@
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/indexus/go-indexus-core/domain"
+)
+
+// Version is the version of the protocol spoken by the node, peers agree on
+// the lowest version of both sides during the handshake.
+const Version = 9
+
+// MinVersion is the oldest version spoken by the node, older peers sign their
+// frames without the nonce of their proof and are reached over HTTP.
+const MinVersion = 3
+
+// Versions changing the encoding of the messages, which follows the version
+// negotiated on the connection.
+const (
+	// policies carry their access rules and items their writer
+	writers = 4
+	// insertions carry the nodes they went through
+	hopping = 5
+	// items carry their idempotency key
+	idempotent = 6
+	// sets carry their version, which Get sends and answers unchanged
+	versioned = 7
+	// the areas handed over carry the idempotency keys of their items
+	witnessing = 8
+	// the areas handed over carry the redirects of the areas below them
+	redirecting = 9
+)
+
+// Preface opens every connection speaking the binary protocol, it is echoed
+// by the server. Its trailing blank line makes an HTTP server answer with an
+// error at once, which tells the client to fall back to HTTP.
+const Preface = "IDXW\r\n\r\n"
+
+const (
+	// MaxFrame is the length of a frame at most once the handshake is done
+	MaxFrame = 64 << 20
+	// MaxHandshake is the length of a frame at most before, so that a peer
+	// not authenticated cannot make the node allocate large buffers
+	MaxHandshake = 64 << 10
+)
+
+type Type byte
+
+const (
+	Hello Type = iota + 1
+	Neighbors
+	Random
+	Transfer
+	Adopt
+	Get
+	New
+	Ok
+	Error
+	Batch
+	Insert
+	Invalidate
+	Changes
+)
+
+// Capabilities lists the optional features supported by a node.
+type Capabilities uint64
+
+const (
+	Adoption Capabilities = 1 << iota
+	// Secure peers require TLS on every call but the handshake
+	Secure
+	// Batching peers accept the insertion of several items in one call
+	Batching
+	// Acknowledgment peers answer an insertion once the item is added
+	Acknowledgment
+	// Invalidation peers accept the sets pushed by their owners
+	Invalidation
+	// Deltas peers answer the changes of a set since a version
+	Deltas
+)
+
+// Supported are the capabilities of this implementation.
+const Supported = Adoption | Secure | Batching | Acknowledgment | Invalidation | Deltas
+
+func (c Capabilities) Has(capability Capabilities) bool {
+	return c&capability == capability
+}
+
+var ErrProtocol = errors.New("peer does not speak the binary protocol")
+
+// Seal prefixes the payload of a frame with its signature by the identity,
+// returned to verify the response. The signature of a response is bound to
+// the signature of the request.
+func Seal(identity *domain.Identity, t Type, payload []byte, request []byte) ([]byte, []byte) {
+	var signature *domain.Signature
+	if identity != nil {
+		signature = identity.Seal(message(t, payload, request))
+	}
+
+	e := NewEncoder()
+	e.Signature(signature)
+	if signature == nil {
+		return append(e.Bytes(), payload...), nil
+	}
+	return append(e.Bytes(), payload...), signature.Value
+}
+
+// Open verifies the signature prefixing the payload of a frame.
+func Open(t Type, frame []byte, request []byte) (*domain.Signature, []byte, error) {
+	d := NewDecoder(frame)
+	signature := d.Signature()
+	if err := d.Err(); err != nil {
+		return nil, nil, err
+	}
+	if signature == nil {
+		return nil, nil, fmt.Errorf("%w: signature required", domain.ErrSignature)
+	}
+
+	payload := d.Rest()
+	if err := signature.Verify(message(t, payload, request)); err != nil {
+		return nil, nil, err
+	}
+	return signature, payload, nil
+}
+
+func message(t Type, payload []byte, request []byte) []byte {
+	content := append([]byte{byte(t)}, payload...)
+	if request == nil {
+		return content
+	}
+	return domain.ResponseMessage(request, content)
+}
+
+// WriteFrame writes a message as its length, its type and its payload.
+func WriteFrame(w io.Writer, t Type, payload []byte) error {
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)+1))
+	header[4] = byte(t)
+
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadFrame reads a message written by WriteFrame, longer ones than the limit
+// being refused.
+func ReadFrame(r *bufio.Reader, limit uint32) (Type, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header)
+	if length == 0 || length > limit {
+		return 0, nil, fmt.Errorf("invalid frame length: %d", length)
+	}
+
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return Type(header[4]), payload, nil
+}
+
+// ReadPreface checks that the connection starts with the preface.
+func ReadPreface(r *bufio.Reader) error {
+	preface := make([]byte, len(Preface))
+	if _, err := io.ReadFull(r, preface); err != nil {
+		return err
+	}
+	if string(preface) != Preface {
+		return ErrProtocol
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestReadFrameRefusesFramesBeyondTheLimit(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteFrame(&buffer, Transfer, make([]byte, MaxHandshake)); err != nil {
+		t.Fatal(err)
+	}
+	frame := buffer.Bytes()
+
+	if _, _, err := ReadFrame(bufio.NewReader(bytes.NewReader(frame)), MaxHandshake); err == nil {
+		t.Error("frame longer than the handshake limit read before the handshake")
+	}
+	if _, payload, err := ReadFrame(bufio.NewReader(bytes.NewReader(frame)), MaxFrame); err != nil || len(payload) != MaxHandshake {
+		t.Errorf("frame read after the handshake as %d bytes, %v", len(payload), err)
+	}
+}
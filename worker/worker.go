@@ -8,10 +8,11 @@ import (
 
 type Service interface {
 	Delay() time.Duration
-	Observe() error
-	Refresh() error
-	Update() error
+	Observe(context.Context) error
+	Refresh(context.Context) error
+	Update(context.Context) error
 	Feed() error
+	Push() error
 }
 
 type Worker struct {
@@ -33,6 +34,11 @@ func (w *Worker) Feed() error {
 	return w.Service.Feed()
 }
 
+func (w *Worker) Push() error {
+	log.Println("Invalidation pushes started")
+	return w.Service.Push()
+}
+
 func (w *Worker) Start() error {
 	log.Println("Recurring jobs started")
 	for {
@@ -40,13 +46,13 @@ func (w *Worker) Start() error {
 		case <-w.ctx.Done():
 			return nil
 		case <-time.After(w.Service.Delay()):
-			if err := w.Service.Observe(); err != nil {
+			if err := w.Service.Observe(w.ctx); err != nil {
 				return err
 			}
-			if err := w.Service.Refresh(); err != nil {
+			if err := w.Service.Refresh(w.ctx); err != nil {
 				return err
 			}
-			if err := w.Service.Update(); err != nil {
+			if err := w.Service.Update(w.ctx); err != nil {
 				return err
 			}
 		}